@@ -0,0 +1,233 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodyBytes bounds how much of an error response is kept
+const maxErrorBodyBytes = 4 << 10
+
+// ErrInvalidResponse means a successful response body could not be decoded
+var ErrInvalidResponse = errors.New("invalid response body")
+
+// Error is a non-success response from the backend
+type Error struct {
+	StatusCode int
+	// Failing hop from the problem, or from X-Timeout-Hop on a 504
+	Hop string
+	// Problem detail, or the trimmed body when the response was not a
+	// problem, e.g. an Envoy RBAC denial
+	Message string
+	// Decoded problem+json body; nil for other content types
+	Problem *Problem
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("backend returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Doer sends HTTP requests. *http.Client satisfies it, as does any wrapper
+// that adds retries or instrumentation.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is a typed client for the backend API. Requests are sent to
+// BaseURL, which in the demo is the caller's local Envoy listener.
+type Client struct {
+	BaseURL    string
+	HTTPClient Doer
+	// UserAgent is sent on every request when set
+	UserAgent string
+}
+
+// NewClient creates a client for the backend at baseURL. A nil doer uses
+// http.DefaultClient.
+func NewClient(baseURL string, doer Doer) *Client {
+	if doer == nil {
+		doer = http.DefaultClient
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: doer,
+	}
+}
+
+// Demo runs the demo flow: GET /api/demo
+func (c *Client) Demo(ctx context.Context) (*DemoResult, error) {
+	var result DemoResult
+	if err := c.do(ctx, http.MethodGet, "/api/demo", nil, http.StatusOK, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DemoScenario runs a negative-path scenario on the backend:
+// GET /api/demo?scenario=name
+func (c *Client) DemoScenario(ctx context.Context, name string) (*DemoResult, error) {
+	var result DemoResult
+	if err := c.do(ctx, http.MethodGet, "/api/demo?scenario="+url.QueryEscape(name), nil, http.StatusOK, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListOrders returns the orders visible to the caller: GET /api/orders.
+// readYourWrites pins the read to the primary database.
+func (c *Client) ListOrders(ctx context.Context, readYourWrites bool) ([]Order, error) {
+	path := "/api/orders"
+	if readYourWrites {
+		path += "?read_your_writes=true"
+	}
+
+	var orders []Order
+	if err := c.do(ctx, http.MethodGet, path, nil, http.StatusOK, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// GetOrder returns one order: GET /api/orders/{id}
+func (c *Client) GetOrder(ctx context.Context, id int) (*Order, error) {
+	var order Order
+	if err := c.do(ctx, http.MethodGet, orderPath(id), nil, http.StatusOK, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// CreateOrder creates a pending order: POST /api/orders
+func (c *Client) CreateOrder(ctx context.Context, req CreateOrderRequest) (*Order, error) {
+	var order Order
+	if err := c.do(ctx, http.MethodPost, "/api/orders", req, http.StatusCreated, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// UpdateOrder changes an order's description and/or status:
+// PUT /api/orders/{id}
+func (c *Client) UpdateOrder(ctx context.Context, id int, req UpdateOrderRequest) (*Order, error) {
+	var order Order
+	if err := c.do(ctx, http.MethodPut, orderPath(id), req, http.StatusOK, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Health returns the backend's liveness and database latency: GET /health
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	var health HealthResponse
+	if err := c.do(ctx, http.MethodGet, "/health", nil, http.StatusOK, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Ready reports whether the backend can serve traffic: GET /ready
+func (c *Client) Ready(ctx context.Context) (*HealthResponse, error) {
+	var health HealthResponse
+	if err := c.do(ctx, http.MethodGet, "/ready", nil, http.StatusOK, &health); err != nil {
+		return nil, err
+	}
+	return &health, nil
+}
+
+// Topology returns the connections the backend has observed:
+// GET /api/topology
+func (c *Client) Topology(ctx context.Context) (*Topology, error) {
+	var topology Topology
+	if err := c.do(ctx, http.MethodGet, "/api/topology", nil, http.StatusOK, &topology); err != nil {
+		return nil, err
+	}
+	return &topology, nil
+}
+
+// PoolStats returns the backend's database connection pool statistics:
+// GET /admin/db/pool
+func (c *Client) PoolStats(ctx context.Context) (*PoolStats, error) {
+	var stats PoolStats
+	if err := c.do(ctx, http.MethodGet, "/admin/db/pool", nil, http.StatusOK, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// do sends a request with an optional JSON body and decodes a response with
+// status want into out. Any other status is returned as *Error.
+func (c *Client) do(ctx context.Context, method, path string, body any, want int, out any) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json, "+ProblemContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return NewError(resp.StatusCode, resp.Header, data)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	return nil
+}
+
+// NewError builds an *Error from a non-success response, decoding the body
+// when it is a problem
+func NewError(status int, header http.Header, body []byte) *Error {
+	apiErr := &Error{
+		StatusCode: status,
+		Hop:        header.Get(HeaderTimeoutHop),
+		Message:    strings.TrimSpace(string(body)),
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	var problem Problem
+	if mediaType == ProblemContentType && json.Unmarshal(body, &problem) == nil {
+		apiErr.Problem = &problem
+		if problem.Hop != "" {
+			apiErr.Hop = problem.Hop
+		}
+		apiErr.Message = problem.Detail
+		if apiErr.Message == "" {
+			apiErr.Message = problem.Title
+		}
+	}
+	return apiErr
+}
+
+func orderPath(id int) string {
+	return "/api/orders/" + strconv.Itoa(id)
+}
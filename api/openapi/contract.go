@@ -0,0 +1,113 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeFor[time.Time]()
+
+// CheckType verifies that a Go type marshals to the named component schema:
+// the same JSON property names, omitempty exactly on the optional ones and
+// compatible types, recursively. contract_test.go runs it on every wire
+// type, and services run it again at startup in debug mode.
+func (d *Document) CheckType(schemaName string, v any) error {
+	s, ok := d.Components.Schemas[schemaName]
+	if !ok {
+		return fmt.Errorf("no schema %q", schemaName)
+	}
+	return d.checkType(reflect.TypeOf(v), s, schemaName, make(map[reflect.Type]bool))
+}
+
+func (d *Document) checkType(t reflect.Type, s *Schema, path string, checked map[reflect.Type]bool) error {
+	s, err := d.schema(s)
+	if err != nil {
+		return err
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	want := ""
+	switch {
+	case t == timeType:
+		if s.Type != "string" || s.Format != "date-time" {
+			return fmt.Errorf("%s: time.Time needs a date-time string schema", path)
+		}
+		return nil
+	case t.Kind() == reflect.Struct:
+		want = "object"
+	case t.Kind() == reflect.Slice:
+		want = "array"
+	case t.Kind() == reflect.String:
+		want = "string"
+	case t.Kind() == reflect.Bool:
+		want = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		want = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		want = "number"
+	default:
+		return fmt.Errorf("%s: unsupported Go type %s", path, t)
+	}
+	if s.Type != want {
+		return fmt.Errorf("%s: Go type %s needs schema type %q, document has %q", path, t, want, s.Type)
+	}
+
+	switch want {
+	case "array":
+		if s.Items == nil {
+			return fmt.Errorf("%s: array schema has no items", path)
+		}
+		return d.checkType(t.Elem(), s.Items, path+"[]", checked)
+	case "object":
+		return d.checkStruct(t, s, path, checked)
+	}
+	return nil
+}
+
+func (d *Document) checkStruct(t reflect.Type, s *Schema, path string, checked map[reflect.Type]bool) error {
+	// Recursive types, e.g. a problem's cause, are checked once
+	if checked[t] {
+		return nil
+	}
+	checked[t] = true
+
+	seen := make(map[string]bool)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		seen[name] = true
+
+		property, ok := s.Properties[name]
+		if !ok {
+			return fmt.Errorf("%s: field %s (%q) is not in the document", path, field.Name, name)
+		}
+		optional := slices.Contains(strings.Split(opts, ","), "omitempty")
+		if required := slices.Contains(s.Required, name); required == optional {
+			return fmt.Errorf("%s.%s: required in the document is %t but omitempty is %t", path, name, required, optional)
+		}
+		if err := d.checkType(field.Type, property, path+"."+name, checked); err != nil {
+			return err
+		}
+	}
+
+	for name := range s.Properties {
+		if !seen[name] {
+			return fmt.Errorf("%s: document property %q has no field in %s", path, name, t)
+		}
+	}
+	return nil
+}
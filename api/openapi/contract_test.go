@@ -0,0 +1,101 @@
+package openapi_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/spire-workload-demo/api/openapi"
+	"github.com/example/spire-workload-demo/internal/backend"
+	"github.com/example/spire-workload-demo/internal/frontend"
+)
+
+// TestWireTypesMatchDocuments checks every component schema in both
+// documents against the Go type the service sends or accepts for it
+func TestWireTypesMatchDocuments(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		load  func() (*openapi.Document, error)
+		types map[string]any
+	}{
+		{"backend", openapi.Backend, backend.OpenAPITypes()},
+		{"frontend", openapi.Frontend, frontend.OpenAPITypes()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := tc.load()
+			if err != nil {
+				t.Fatalf("load document: %v", err)
+			}
+
+			for name := range doc.Components.Schemas {
+				if _, ok := tc.types[name]; !ok {
+					t.Errorf("schema %s has no wire type", name)
+				}
+			}
+			for name, v := range tc.types {
+				if err := doc.CheckType(name, v); err != nil {
+					t.Errorf("%s: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+// TestCheckTypeDetectsDrift checks that the kinds of drift the contract
+// test relies on are reported
+func TestCheckTypeDetectsDrift(t *testing.T) {
+	doc, err := openapi.Backend()
+	if err != nil {
+		t.Fatalf("load document: %v", err)
+	}
+
+	type order struct {
+		ID          int       `json:"id"`
+		Description string    `json:"description"`
+		Status      string    `json:"status"`
+		CreatedAt   time.Time `json:"created_at"`
+	}
+	if err := doc.CheckType("Order", order{}); err != nil {
+		t.Fatalf("matching type rejected: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"missing field", struct {
+			ID          int       `json:"id"`
+			Description string    `json:"description"`
+			CreatedAt   time.Time `json:"created_at"`
+		}{}, `"status" has no field`},
+		{"extra field", struct {
+			ID          int       `json:"id"`
+			Description string    `json:"description"`
+			Status      string    `json:"status"`
+			CreatedAt   time.Time `json:"created_at"`
+			Owner       string    `json:"owner"`
+		}{}, "is not in the document"},
+		{"wrong type", struct {
+			ID          string    `json:"id"`
+			Description string    `json:"description"`
+			Status      string    `json:"status"`
+			CreatedAt   time.Time `json:"created_at"`
+		}{}, `schema type "string"`},
+		{"optional required field", struct {
+			ID          int       `json:"id,omitempty"`
+			Description string    `json:"description"`
+			Status      string    `json:"status"`
+			CreatedAt   time.Time `json:"created_at"`
+		}{}, "omitempty is true"},
+	} {
+		err := doc.CheckType("Order", tc.v)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tc.name, err, tc.want)
+		}
+	}
+
+	if err := doc.CheckType("NoSuchSchema", order{}); err == nil {
+		t.Error("unknown schema accepted")
+	}
+}
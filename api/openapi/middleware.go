@@ -0,0 +1,197 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// maxBodyBytes bounds request bodies read for validation
+const maxBodyBytes = 1 << 20
+
+// Options configures the validation middleware
+type Options struct {
+	// ValidateResponses buffers and checks every response as well; meant
+	// for debug mode
+	ValidateResponses bool
+	// OnResponseViolation is called when a response breaks the document.
+	// The response has already been sent unchanged.
+	OnResponseViolation func(r *http.Request, status int, err error)
+}
+
+// Middleware rejects requests that break the document with 400, or 405 for
+// undocumented methods on documented paths. Paths the document does not
+// cover, such as UI pages, pass through unchecked.
+func (d *Document) Middleware(next http.Handler, opts Options) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template, pathValues, ok := d.match(r.URL.Path)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op := d.operation(template, r.Method)
+		if op == nil {
+			w.Header().Set("Allow", d.allowed(template))
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemMethodNotAllowed, http.StatusMethodNotAllowed,
+				r.Method+" is not allowed on "+template))
+			return
+		}
+
+		if err := d.validateRequest(r, op, pathValues); err != nil {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, err.Error()))
+			return
+		}
+
+		if !opts.ValidateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		if err := d.validateResponse(op, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes()); err != nil && opts.OnResponseViolation != nil {
+			opts.OnResponseViolation(r, rec.status, err)
+		}
+	})
+}
+
+// validateRequest checks parameters and the JSON body, restoring the body
+// for the handler
+func (d *Document) validateRequest(r *http.Request, op *Operation, pathValues map[string]string) error {
+	query := r.URL.Query()
+	for _, p := range op.Parameters {
+		p = d.parameter(p)
+
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = pathValues[p.Name]
+		case "query":
+			present = query.Has(p.Name)
+			raw = query.Get(p.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if p.Required {
+				return invalid(p.Name, "required %s parameter is missing", p.In)
+			}
+			continue
+		}
+		if p.Schema != nil {
+			if err := d.validateParameter(raw, p.Schema, p.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	r.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(body) > maxBodyBytes {
+		return fmt.Errorf("body exceeds %d bytes", maxBodyBytes)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return invalid("$", "request body is required")
+		}
+		return nil
+	}
+
+	value, err := decode(body)
+	if err != nil {
+		return invalid("$", "invalid JSON: %v", err)
+	}
+	return d.validate(value, media.Schema, "$")
+}
+
+// validateResponse checks a response's status, content type and JSON body
+func (d *Document) validateResponse(op *Operation, status int, contentType string, body []byte) error {
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		resp = op.Responses["default"]
+	}
+	if resp == nil {
+		return fmt.Errorf("undocumented status %d", status)
+	}
+	resp = d.response(resp)
+	if len(resp.Content) == 0 || len(body) == 0 {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q", contentType)
+	}
+	media, ok := resp.Content[mediaType]
+	if !ok {
+		return fmt.Errorf("undocumented content type %q for status %d", mediaType, status)
+	}
+	if media.Schema == nil || (mediaType != "application/json" && mediaType != api.ProblemContentType) {
+		return nil
+	}
+
+	value, err := decode(body)
+	if err != nil {
+		return invalid("$", "invalid JSON: %v", err)
+	}
+	return d.validate(value, media.Schema, "$")
+}
+
+// decode parses JSON keeping numbers exact, so integers can be told apart
+func decode(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// responseRecorder copies the response body while passing it through
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,210 @@
+// Package openapi embeds the OpenAPI 3 documents for the frontend and
+// backend APIs, serves them and validates traffic against them. Validation
+// covers the subset of OpenAPI the documents use: path, query and JSON body
+// schemas with type, format, enum, required, additionalProperties, items,
+// length and range keywords, and local $refs.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+//go:embed backend.json frontend.json
+var documents embed.FS
+
+// Document is a parsed OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	raw    []byte
+	routes []route
+}
+
+// Components holds the reusable definitions referenced with $ref
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+	Responses  map[string]*Response  `json:"responses"`
+}
+
+// Operation is one method on a path
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Parameters  []*Parameter         `json:"parameters"`
+	RequestBody *RequestBody         `json:"requestBody"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation's body
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes one response status
+type Response struct {
+	Ref     string               `json:"$ref"`
+	Content map[string]MediaType `json:"content"`
+}
+
+// MediaType holds the schema for one content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the supported subset of an OpenAPI schema object
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+}
+
+// route is a path template split into segments for matching
+type route struct {
+	template string
+	segments []string
+}
+
+// Backend returns the backend API document
+func Backend() (*Document, error) {
+	return load("backend.json")
+}
+
+// Frontend returns the frontend API document
+func Frontend() (*Document, error) {
+	return load("frontend.json")
+}
+
+func load(name string) (*Document, error) {
+	data, err := documents.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{raw: data}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document %s: %w", name, err)
+	}
+
+	for template := range doc.Paths {
+		doc.routes = append(doc.routes, route{
+			template: template,
+			segments: strings.Split(strings.Trim(template, "/"), "/"),
+		})
+	}
+	// Literal segments win over parameters, as in http.ServeMux
+	sort.Slice(doc.routes, func(i, j int) bool {
+		return strings.Count(doc.routes[i].template, "{") < strings.Count(doc.routes[j].template, "{")
+	})
+	return doc, nil
+}
+
+// ServeHTTP serves the document as /openapi.json
+func (d *Document) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(d.raw)
+}
+
+// match finds the path template for a request path and its path values
+func (d *Document) match(path string) (string, map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for _, rt := range d.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+		values := make(map[string]string)
+		matched := true
+		for i, segment := range rt.segments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && segments[i] != "" {
+				values[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rt.template, values, true
+		}
+	}
+	return "", nil, false
+}
+
+// operation returns the operation for method on template, treating HEAD as
+// GET like http.ServeMux does
+func (d *Document) operation(template, method string) *Operation {
+	item := d.Paths[template]
+	if op := item[strings.ToLower(method)]; op != nil {
+		return op
+	}
+	if method == http.MethodHead {
+		return item["get"]
+	}
+	return nil
+}
+
+// allowed lists the methods documented for template
+func (d *Document) allowed(template string) string {
+	var methods []string
+	for method := range d.Paths[template] {
+		methods = append(methods, strings.ToUpper(method))
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
+}
+
+func (d *Document) parameter(p *Parameter) *Parameter {
+	if name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/"); ok {
+		if resolved := d.Components.Parameters[name]; resolved != nil {
+			return resolved
+		}
+	}
+	return p
+}
+
+func (d *Document) response(r *Response) *Response {
+	if name, ok := strings.CutPrefix(r.Ref, "#/components/responses/"); ok {
+		if resolved := d.Components.Responses[name]; resolved != nil {
+			return resolved
+		}
+	}
+	return r
+}
+
+func (d *Document) schema(s *Schema) (*Schema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+	if !ok || d.Components.Schemas[name] == nil {
+		return nil, fmt.Errorf("unresolved $ref %q", s.Ref)
+	}
+	return d.Components.Schemas[name], nil
+}
@@ -0,0 +1,161 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError reports the location in a document that broke the schema
+type ValidationError struct {
+	// JSON path of the offending value, e.g. $.orders[2].status
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func invalid(path, format string, args ...any) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// validate checks a value decoded from JSON with UseNumber against s
+func (d *Document) validate(value any, s *Schema, path string) error {
+	s, err := d.schema(s)
+	if err != nil {
+		return err
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return enumEqual(e, value) }) {
+		return invalid(path, "must be one of %v", s.Enum)
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return invalid(path, "must be an object")
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return invalid(path, "missing required property %q", name)
+			}
+		}
+		for name, v := range object {
+			property, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return invalid(path, "unknown property %q", name)
+				}
+				continue
+			}
+			if err := d.validate(v, property, path+"."+name); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return invalid(path, "must be an array")
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range items {
+			if err := d.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid(path, "must be a string")
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			return invalid(path, "must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return invalid(path, "must be at most %d characters", *s.MaxLength)
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, str); err != nil {
+				return invalid(path, "must be an RFC 3339 date-time")
+			}
+		}
+
+	case "integer", "number":
+		num, ok := value.(json.Number)
+		if !ok {
+			return invalid(path, "must be a %s", s.Type)
+		}
+		f, err := num.Float64()
+		if err != nil {
+			return invalid(path, "must be a %s", s.Type)
+		}
+		if s.Type == "integer" && f != math.Trunc(f) {
+			return invalid(path, "must be an integer")
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return invalid(path, "must be at least %g", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return invalid(path, "must be at most %g", *s.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(path, "must be a boolean")
+		}
+
+	default:
+		return fmt.Errorf("unsupported schema type %q", s.Type)
+	}
+	return nil
+}
+
+// validateParameter checks a raw path or query value against s, converting
+// it to the schema's type first
+func (d *Document) validateParameter(raw string, s *Schema, path string) error {
+	s, err := d.schema(s)
+	if err != nil {
+		return err
+	}
+
+	var value any = raw
+	switch s.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return invalid(path, "must be a %s", s.Type)
+		}
+		value = json.Number(raw)
+	case "boolean":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return invalid(path, "must be a boolean")
+		}
+		value = b
+	}
+	return d.validate(value, s, path)
+}
+
+// enumEqual compares an enum entry from the document with a decoded value
+func enumEqual(enum, value any) bool {
+	if num, ok := value.(json.Number); ok {
+		f, err := num.Float64()
+		return err == nil && reflect.DeepEqual(enum, f)
+	}
+	return reflect.DeepEqual(enum, value)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/example/spire-workload-demo/internal/reqctx"
+)
+
+// ProblemContentType is the media type of Problem responses (RFC 7807)
+const ProblemContentType = "application/problem+json"
+
+// problemTypeBase prefixes every problem type URI
+const problemTypeBase = "https://example.org/problems/"
+
+// Problem types shared by the frontend and backend. Each is the last segment
+// of the problem's type URI.
+const (
+	ProblemInvalidRequest      = "invalid-request"
+	ProblemNotFound            = "not-found"
+	ProblemMethodNotAllowed    = "method-not-allowed"
+	ProblemForbidden           = "forbidden"
+	ProblemDatabaseUnavailable = "database-unavailable"
+	ProblemDeadlineExceeded    = "deadline-exceeded"
+	ProblemBackendUnreachable  = "backend-unreachable"
+	ProblemBackendError        = "backend-error"
+	ProblemInternal            = "internal-error"
+	ProblemScenarioUnavailable = "scenario-unavailable"
+	ProblemLoadTestRunning     = "load-test-running"
+	ProblemRateLimited         = "rate-limited"
+)
+
+var problemTitles = map[string]string{
+	ProblemInvalidRequest:      "Invalid request",
+	ProblemNotFound:            "Not found",
+	ProblemMethodNotAllowed:    "Method not allowed",
+	ProblemForbidden:           "Caller not authorized",
+	ProblemDatabaseUnavailable: "Database unavailable",
+	ProblemDeadlineExceeded:    "Deadline exceeded",
+	ProblemBackendUnreachable:  "Backend unreachable",
+	ProblemBackendError:        "Backend request failed",
+	ProblemInternal:            "Internal error",
+	ProblemScenarioUnavailable: "Scenario not available",
+	ProblemLoadTestRunning:     "Load test already running",
+	ProblemRateLimited:         "Too many requests",
+}
+
+// Problem is an RFC 7807 problem details object, the body of every error
+// response from either service
+type Problem struct {
+	// URI identifying the problem type; see ProblemTypeURI
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Request path that produced the problem
+	Instance      string `json:"instance,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Hop that failed, e.g. backend-to-database; empty when the request
+	// itself was at fault
+	Hop string `json:"hop,omitempty"`
+	// Seconds until the request may be retried, sent as Retry-After too
+	RetryAfter int `json:"retry_after,omitempty"`
+	// Upstream problem this one wraps, set by the frontend
+	Cause *Problem `json:"cause,omitempty"`
+}
+
+// NewProblem creates a problem of the given type, e.g. ProblemNotFound
+func NewProblem(problemType string, status int, detail string) *Problem {
+	return &Problem{
+		Type:   ProblemTypeURI(problemType),
+		Title:  problemTitles[problemType],
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// ProblemTypeURI returns the type URI for a problem type
+func ProblemTypeURI(problemType string) string {
+	return problemTypeBase + problemType
+}
+
+// WithHop sets the failing hop and returns p
+func (p *Problem) WithHop(hop string) *Problem {
+	p.Hop = hop
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%d %s", p.Status, p.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+// WriteProblem writes p as the response, filling in the request path and
+// correlation ID
+func WriteProblem(w http.ResponseWriter, r *http.Request, p *Problem) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	if p.CorrelationID == "" {
+		p.CorrelationID = reqctx.CorrelationID(r.Context())
+	}
+	if p.Status == http.StatusGatewayTimeout && p.Hop != "" {
+		w.Header().Set(HeaderTimeoutHop, p.Hop)
+	}
+	if p.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(p.RetryAfter))
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
@@ -0,0 +1,274 @@
+// Package api defines the backend's wire types and a typed client for its
+// HTTP API. The frontend uses it to call the backend through Envoy; other
+// workloads can import it instead of hand-rolling requests.
+package api
+
+import "time"
+
+// Order represents a demo order entity
+type Order struct {
+	ID          int       `json:"id"`
+	Description string    `json:"description"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Order status constants
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// CreateOrderRequest is the body of POST /api/orders
+type CreateOrderRequest struct {
+	Description string `json:"description"`
+}
+
+// UpdateOrderRequest is the body of PUT /api/orders/{id}; omitted fields
+// are left unchanged
+type UpdateOrderRequest struct {
+	Description *string `json:"description,omitempty"`
+	Status      *string `json:"status,omitempty"`
+}
+
+// ConnectionStatus represents the status of a connection attempt
+type ConnectionStatus struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+	Pattern string `json:"pattern"` // "envoy-sds" or "spiffe-helper"
+	// Database circuit breaker state: "closed", "open" or "half-open"
+	CircuitState string `json:"circuit_state,omitempty"`
+	// Time spent on this hop as measured by its client side; includes the
+	// downstream hops it waited on
+	LatencyMs float64 `json:"latency_ms,omitempty"`
+	// Set by the backend on the database hop: time the request waited for
+	// a connection from its pool, zero if one was free
+	PoolWaitMs float64 `json:"pool_wait_ms,omitempty"`
+}
+
+// DemoResult represents the result of the full demo flow
+type DemoResult struct {
+	FrontendToBackend ConnectionStatus `json:"frontend_to_backend"`
+	BackendToDatabase ConnectionStatus `json:"backend_to_database"`
+	Orders            []Order          `json:"orders,omitempty"`
+	Timestamp         time.Time        `json:"timestamp"`
+	// Set when the run was a negative-path scenario
+	Scenario *ScenarioReport `json:"scenario,omitempty"`
+}
+
+// Negative-path demo scenarios, selected with /api/demo?scenario=. Each
+// presents an identity that should be rejected; the demo reports whether it
+// was.
+const (
+	// A valid SVID from the trust domain whose SPIFFE ID Envoy RBAC on the
+	// backend does not allow: expect 403
+	ScenarioImpostorIdentity = "impostor-identity"
+	// An SVID from the trust domain that has expired: expect the backend's
+	// Envoy to reject the TLS handshake
+	ScenarioExpiredCert = "expired-cert"
+	// An SVID issued by a CA outside the trust domain: expect the backend's
+	// Envoy to reject the TLS handshake
+	ScenarioWrongTrustDomain = "wrong-trust-domain"
+	// A database client certificate for a SPIFFE ID PostgreSQL does not map
+	// to a database user: expect the connection to be refused. Run by the
+	// backend.
+	ScenarioUnauthorizedDBClient = "unauthorized-db-client"
+)
+
+// ScenarioReport is the expected and observed outcome of a negative-path
+// scenario
+type ScenarioReport struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Hop the rejected identity was presented on
+	Hop string `json:"hop"`
+	// SPIFFE ID in the secondary client's certificate
+	SPIFFEID string `json:"spiffe_id,omitempty"`
+	Expected string `json:"expected"`
+	Observed string `json:"observed"`
+	// Passed is true when the identity was rejected as expected
+	Passed bool `json:"passed"`
+}
+
+// Topology is the graph of workloads and the connections observed between
+// them, built from the connection events each service logs
+type Topology struct {
+	Nodes []TopologyNode `json:"nodes"`
+	Edges []TopologyEdge `json:"edges"`
+	// Set by the frontend when the backend's half of the graph could not be
+	// fetched; the graph then only holds what the frontend observed
+	BackendError string `json:"backend_error,omitempty"`
+	// Pods whose observations the graph holds, e.g. "backend/backend-6c9f-x2lq".
+	// Each pod records only its own connections and the backend runs several
+	// replicas, so the backend's half comes from whichever pod answered.
+	Observers []string `json:"observers"`
+	// Set when a pod stopped recording new connections at its cap
+	Truncated   bool      `json:"truncated,omitempty"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// TopologyNode is a workload, identified by its SPIFFE ID. Peers that never
+// presented one, e.g. a target the workload failed to reach, are identified
+// by their address instead.
+type TopologyNode struct {
+	ID       string `json:"id"`
+	SPIFFEID string `json:"spiffe_id,omitempty"`
+	// Service account name from the SPIFFE ID, or the address
+	Workload string `json:"workload"`
+	// Integration patterns of the connections the workload took part in
+	Patterns []string `json:"patterns"`
+}
+
+// TopologyEdge is the observed connections from one workload to another over
+// one integration pattern
+type TopologyEdge struct {
+	From         string     `json:"from"`
+	To           string     `json:"to"`
+	Pattern      string     `json:"pattern"`
+	SuccessCount int64      `json:"success_count"`
+	FailureCount int64      `json:"failure_count"`
+	LastSuccess  *time.Time `json:"last_success,omitempty"`
+	LastFailure  *time.Time `json:"last_failure,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// PoolStats is a sample of the backend's sql.DB.Stats() alongside the
+// configured pool limits (FR-020), returned by /admin/db/pool
+type PoolStats struct {
+	SampledAt time.Time `json:"sampled_at"`
+	// Configured limits
+	MaxOpenConns    int    `json:"max_open_conns"`
+	MaxIdleConns    int    `json:"max_idle_conns"`
+	ConnMaxLifetime string `json:"conn_max_lifetime"`
+	// Current pool state
+	OpenConnections int `json:"open_connections"`
+	InUse           int `json:"in_use"`
+	Idle            int `json:"idle"`
+	// Cumulative counters since startup
+	WaitCount         int64   `json:"wait_count"`
+	WaitDurationMS    float64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64   `json:"max_lifetime_closed"`
+}
+
+// LoadTestRequest is the body of POST /api/loadtest on the frontend
+type LoadTestRequest struct {
+	// Demo runs started per second
+	Rate float64 `json:"rate"`
+	// Maximum demo runs in flight; a run due while all are busy is skipped
+	Concurrency int `json:"concurrency"`
+	// Length of the run as a Go duration, e.g. "30s"
+	Duration string `json:"duration"`
+}
+
+// Load test states
+const (
+	LoadTestRunning   = "running"
+	LoadTestCompleted = "completed"
+	LoadTestCancelled = "cancelled"
+)
+
+// LoadTestReport is the progress or outcome of a load test. Latencies are
+// in milliseconds.
+type LoadTestReport struct {
+	State      string          `json:"state"`
+	Request    LoadTestRequest `json:"request"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	ElapsedMs  float64         `json:"elapsed_ms"`
+	// Completed demo runs
+	Requests  int64 `json:"requests"`
+	Successes int64 `json:"successes"`
+	Failures  int64 `json:"failures"`
+	// Runs not started because every worker was busy: the target rate was
+	// not reached
+	Skipped      int64           `json:"skipped"`
+	AchievedRate float64         `json:"achieved_rate"`
+	Latency      LoadTestLatency `json:"latency"`
+	Errors       []LoadTestError `json:"errors"`
+	// Backend connection pool waits reported on the runs so far
+	PoolWait *PoolWait `json:"pool_wait,omitempty"`
+	// Why pool_wait is missing, e.g. no run reached the database
+	PoolWaitError string `json:"pool_wait_error,omitempty"`
+	// Per-second counts and end-to-end latency, to spot spikes such as a
+	// certificate rotation
+	Timeline []LoadTestSecond `json:"timeline"`
+}
+
+// LoadTestLatency holds latency percentiles per hop. Each hop's latency
+// includes the hops it waited on.
+type LoadTestLatency struct {
+	EndToEnd          LatencySummary `json:"end_to_end"`
+	FrontendToBackend LatencySummary `json:"frontend_to_backend"`
+	BackendToDatabase LatencySummary `json:"backend_to_database"`
+}
+
+// LatencySummary is a latency distribution in milliseconds
+type LatencySummary struct {
+	Count int     `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// LoadTestError counts failed runs by the pattern and hop that failed
+type LoadTestError struct {
+	Pattern string `json:"pattern"`
+	Hop     string `json:"hop"`
+	// Problem type, or how the database hop failed inside a 200 response
+	Reason string `json:"reason"`
+	Count  int64  `json:"count"`
+	// Most recent failure message
+	Example string `json:"example"`
+}
+
+// LoadTestSecond summarizes the runs that started in one second of a load
+// test
+type LoadTestSecond struct {
+	Second   int     `json:"second"`
+	Requests int64   `json:"requests"`
+	Failures int64   `json:"failures"`
+	P50      float64 `json:"p50"`
+	P99      float64 `json:"p99"`
+}
+
+// PoolWait sums the pool waits the backend reported on each demo run of a
+// load test, whichever backend replica answered it
+type PoolWait struct {
+	// Runs with a database result from the backend
+	Runs int64 `json:"runs"`
+	// Runs that waited for a pooled connection
+	WaitCount      int64   `json:"wait_count"`
+	WaitDurationMS float64 `json:"wait_duration_ms"`
+	// Average wait of the runs that had to wait
+	MeanWaitMS float64 `json:"mean_wait_ms"`
+	MaxWaitMS  float64 `json:"max_wait_ms"`
+}
+
+// HealthResponse is returned by /health and /ready
+type HealthResponse struct {
+	Component string `json:"component"`
+	Status    string `json:"status"`
+	// Set by the backend: duration of its database ping
+	DatabaseLatencyMs float64 `json:"database_latency_ms,omitempty"`
+}
+
+// Deadline propagation headers shared by the frontend and backend
+const (
+	// HeaderRequestBudget carries the caller's remaining time budget in
+	// milliseconds. A relative budget avoids clock skew between pods.
+	HeaderRequestBudget = "X-Request-Budget-Ms"
+	// HeaderTimeoutHop names the hop that ran out of time on a 504 response
+	HeaderTimeoutHop = "X-Timeout-Hop"
+)
+
+// Hops reported when a deadline is exceeded
+const (
+	HopFrontendToBackend = "frontend-to-backend"
+	HopBackendToDatabase = "backend-to-database"
+)
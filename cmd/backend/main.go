@@ -6,10 +6,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/example/spire-workload-demo/api/openapi"
 	"github.com/example/spire-workload-demo/internal/backend"
+	"github.com/example/spire-workload-demo/internal/reqctx"
 )
 
 func main() {
@@ -18,10 +21,15 @@ func main() {
 	logger.Info("Starting backend service")
 
 	// Load database configuration from environment
-	dbConfig := backend.NewDBConfigFromEnv()
+	dbConfig, err := backend.NewDBConfigFromEnv()
+	if err != nil {
+		logger.Error("Invalid database configuration", "error", err)
+		os.Exit(1)
+	}
 	
 	// Log configuration (without sensitive data)
 	logger.Info("Database configuration loaded",
+		"driver", dbConfig.Driver,
 		"host", dbConfig.Host,
 		"port", dbConfig.Port,
 		"dbname", dbConfig.DBName,
@@ -31,32 +39,120 @@ func main() {
 		"ssl_cert", dbConfig.SSLCert,
 		"ssl_key", dbConfig.SSLKey,
 		"ssl_root_ca", dbConfig.SSLRootCA,
+		"sslmode", dbConfig.SSLMode,
+		"server_spiffe_id", dbConfig.ServerSPIFFEID,
+		"rls_enabled", dbConfig.RLSEnabled,
+		"rls_scope", dbConfig.RLSScope,
+		"role_mapping_enabled", dbConfig.RoleMapping.Enabled,
+		"role_mappings", len(dbConfig.RoleMapping.Roles),
+		"role_fallback", dbConfig.RoleMapping.Fallback,
+		"read_replicas", len(dbConfig.ReplicaRouting.Replicas),
+		"replica_max_lag", dbConfig.ReplicaRouting.MaxLag,
+		"query_timeouts", dbConfig.QueryTimeouts,
+		"breaker_failure_threshold", dbConfig.Resilience.FailureThreshold,
+		"breaker_open_timeout", dbConfig.Resilience.OpenTimeout,
+		"bulkhead_limit", dbConfig.Resilience.BulkheadLimit,
+		"slow_query_threshold", dbConfig.QueryLog.SlowThreshold,
+		"slow_query_explain_rate", dbConfig.QueryLog.ExplainSampleRate,
 	)
 
-	// Connect to database with Pattern 2 (spiffe-helper client certificates)
-	ctx := context.Background()
-	db, err := backend.NewDB(ctx, dbConfig, logger)
+	// Create HTTP handlers; the server starts before the database is reachable
+	// and reports not-ready on /ready until the connection succeeds
+	handler := backend.NewHandler(dbConfig, logger)
+	defer handler.Close()
+
+	// Connect to database with Pattern 2 (spiffe-helper client certificates).
+	// NewDB waits for the sidecar's certificates and retries with backoff.
+	ctx, cancelStartup := context.WithCancel(context.Background())
+	defer cancelStartup()
+	workerConfig := backend.NewWorkerConfigFromEnv()
+	startupErrors := make(chan error, 1)
+	go func() {
+		db, err := backend.NewDB(ctx, dbConfig, logger)
+		if err != nil {
+			startupErrors <- err
+			return
+		}
+
+		logger.Info("Database connection established successfully",
+			"pattern", db.Pattern(),
+		)
+
+		// Bring the schema up to date before serving traffic
+		if dbConfig.AutoMigrate {
+			if err := db.Migrate(ctx); err != nil {
+				db.Close()
+				startupErrors <- fmt.Errorf("failed to apply schema migrations: %w", err)
+				return
+			}
+		}
+
+		handler.SetDB(db)
+		go db.MonitorPool(ctx)
+		go db.MonitorReplicas(ctx)
+
+		// Start the order-processing worker; replicas compete for an advisory lock
+		if workerConfig.Enabled {
+			go backend.NewWorker(db, workerConfig, logger).Run(ctx)
+		}
+	}()
+
+	// Load the API document; LOG_LEVEL=debug also checks the wire types
+	// against it and validates every response
+	apiDoc, err := openapi.Backend()
 	if err != nil {
-		logger.Error("Failed to connect to database", "error", err)
+		logger.Error("Failed to load OpenAPI document", "error", err)
 		os.Exit(1)
 	}
-	defer db.Close()
-
-	logger.Info("Database connection established successfully",
-		"pattern", backend.PatternSpiffeHelper,
-	)
+	debug := strings.EqualFold(getEnv("LOG_LEVEL", "info"), "debug")
+	if debug {
+		if err := backend.CheckOpenAPI(apiDoc); err != nil {
+			logger.Error("Wire types do not match the OpenAPI document", "event", backend.EventOpenAPIViolation, "error", err)
+		}
+	}
 
-	// Create HTTP handlers
-	handler := backend.NewHandler(db, logger)
+	// Per-caller rate limits, reloaded from RATE_LIMIT_FILE while running
+	if err := handler.StartRateLimiter(ctx); err != nil {
+		logger.Error("Invalid rate limit configuration", "error", err)
+		os.Exit(1)
+	}
 
 	// Setup HTTP router with logging middleware
 	mux := http.NewServeMux()
 	mux.HandleFunc("/health", handler.HealthHandler)
-	mux.HandleFunc("/api/orders", handler.OrdersHandler)
+	mux.HandleFunc("/ready", handler.ReadyHandler)
+	mux.HandleFunc("GET /api/orders", handler.OrdersHandler)
+	mux.HandleFunc("POST /api/orders", handler.CreateOrderHandler)
+	mux.HandleFunc("GET /api/orders/{id}", handler.OrderHandler)
+	mux.HandleFunc("PUT /api/orders/{id}", handler.UpdateOrderHandler)
 	mux.HandleFunc("/api/demo", handler.DemoHandler)
-
-	// Wrap with logging middleware
-	httpHandler := handler.LoggingMiddleware(mux)
+	mux.HandleFunc("GET /api/topology", handler.TopologyHandler)
+	mux.HandleFunc("/admin/db/roles", handler.RolesHandler)
+	mux.HandleFunc("/admin/db/pool", handler.PoolHandler)
+	mux.HandleFunc("/metrics", handler.MetricsHandler)
+	mux.Handle("/openapi.json", apiDoc)
+
+	// Wrap with request-scoping middleware (innermost first), then logging;
+	// the correlation ID is outermost so every log line carries it
+	httpHandler := handler.EndpointMiddleware(mux)
+	httpHandler = handler.ReadPreferenceMiddleware(httpHandler)
+	httpHandler = handler.DeadlineMiddleware(httpHandler)
+	httpHandler = handler.RateLimitMiddleware(mux, httpHandler)
+	httpHandler = handler.IdentityMiddleware(httpHandler)
+	httpHandler = apiDoc.Middleware(httpHandler, openapi.Options{
+		ValidateResponses: debug,
+		OnResponseViolation: func(r *http.Request, status int, err error) {
+			logger.ErrorContext(r.Context(), "Response violates the OpenAPI document",
+				"event", backend.EventOpenAPIViolation,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"error", err,
+			)
+		},
+	})
+	httpHandler = handler.LoggingMiddleware(httpHandler)
+	httpHandler = reqctx.Middleware(httpHandler)
 
 	// Configure HTTP server
 	port := getEnv("PORT", "9090")
@@ -73,7 +169,7 @@ func main() {
 	go func() {
 		logger.Info("Backend HTTP server starting",
 			"port", port,
-			"endpoints", []string{"/health", "/api/orders", "/api/demo"},
+			"endpoints", []string{"/health", "/ready", "/api/orders", "/api/orders/{id}", "/api/demo", "/api/topology", "/admin/db/roles", "/admin/db/pool", "/metrics", "/openapi.json"},
 		)
 		serverErrors <- server.ListenAndServe()
 	}()
@@ -87,8 +183,13 @@ func main() {
 		logger.Error("Server error", "error", err)
 		os.Exit(1)
 
+	case err := <-startupErrors:
+		logger.Error("Startup failed", "error", err)
+		os.Exit(1)
+
 	case sig := <-shutdown:
 		logger.Info("Shutdown signal received", "signal", sig)
+		cancelStartup()
 
 		// Give outstanding requests a deadline for completion
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -6,10 +6,13 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/example/spire-workload-demo/api/openapi"
 	"github.com/example/spire-workload-demo/internal/frontend"
+	"github.com/example/spire-workload-demo/internal/reqctx"
 )
 
 func main() {
@@ -18,7 +21,34 @@ func main() {
 	logger.Info("Starting frontend service")
 
 	// Create handler with dependencies
-	handler := frontend.NewHandler(logger)
+	handler, err := frontend.NewHandler(logger)
+	if err != nil {
+		logger.Error("Invalid frontend configuration", "error", err.Error())
+		os.Exit(1)
+	}
+
+	// Per-client rate limits, reloaded from RATE_LIMIT_FILE while running
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := handler.StartRateLimiter(ctx); err != nil {
+		logger.Error("Invalid rate limit configuration", "error", err.Error())
+		os.Exit(1)
+	}
+	handler.StartHistory(ctx)
+
+	// Load the API document; LOG_LEVEL=debug also checks the wire types
+	// against it and validates every response
+	apiDoc, err := openapi.Frontend()
+	if err != nil {
+		logger.Error("Failed to load OpenAPI document", "error", err.Error())
+		os.Exit(1)
+	}
+	debug := strings.EqualFold(os.Getenv("LOG_LEVEL"), "debug")
+	if debug {
+		if err := frontend.CheckOpenAPI(apiDoc); err != nil {
+			logger.Error("Wire types do not match the OpenAPI document", "event", frontend.EventOpenAPIViolation, "error", err.Error())
+		}
+	}
 
 	// Configure HTTP router
 	mux := http.NewServeMux()
@@ -26,11 +56,40 @@ func main() {
 	// Routes
 	mux.HandleFunc("/", handler.IndexHandler())
 	mux.HandleFunc("/static/", handler.StaticHandler())
+	mux.HandleFunc("POST /demo/run", handler.DemoRunHandler())
 	mux.HandleFunc("/api/demo", handler.DemoHandler())
+	mux.HandleFunc("GET /api/demo/history", handler.DemoHistoryHandler())
+	mux.HandleFunc("GET /api/demo/history/{correlation_id}", handler.DemoHistoryRecordHandler())
+	mux.HandleFunc("GET /api/topology", handler.TopologyHandler())
+	mux.HandleFunc("POST /api/loadtest", handler.LoadTestStartHandler())
+	mux.HandleFunc("GET /api/loadtest", handler.LoadTestHandler())
+	mux.HandleFunc("DELETE /api/loadtest", handler.LoadTestStopHandler())
+	mux.HandleFunc("GET /api/orders", handler.ListOrdersHandler())
+	mux.HandleFunc("POST /api/orders", handler.CreateOrderHandler())
+	mux.HandleFunc("GET /api/orders/{id}", handler.GetOrderHandler())
+	mux.HandleFunc("PUT /api/orders/{id}", handler.UpdateOrderHandler())
 	mux.HandleFunc("/health", handler.HealthHandler())
-
-	// Wrap with logging middleware
-	wrappedMux := handler.LoggingMiddleware(mux)
+	mux.HandleFunc("/ready", handler.ReadyHandler())
+	mux.HandleFunc("/health/deep", handler.DeepHealthHandler())
+	mux.HandleFunc("/metrics", handler.MetricsHandler())
+	mux.Handle("/openapi.json", apiDoc)
+
+	// Rate limit, validate against the API document, then wrap with logging
+	// middleware, inside the correlation ID middleware so every log line
+	// carries the ID
+	validated := apiDoc.Middleware(handler.RateLimitMiddleware(mux, mux), openapi.Options{
+		ValidateResponses: debug,
+		OnResponseViolation: func(r *http.Request, status int, err error) {
+			logger.ErrorContext(r.Context(), "Response violates the OpenAPI document",
+				"event", frontend.EventOpenAPIViolation,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", status,
+				"error", err.Error(),
+			)
+		},
+	})
+	wrappedMux := reqctx.Middleware(handler.LoggingMiddleware(validated))
 
 	// Get port from environment
 	port := os.Getenv("PORT")
@@ -54,7 +113,7 @@ func main() {
 	go func() {
 		logger.Info("Frontend HTTP server starting",
 			"port", port,
-			"endpoints", []string{"/", "/static/*", "/api/demo", "/health"},
+			"endpoints", []string{"/", "/static/*", "/demo/run", "/api/demo", "/api/demo/history", "/api/demo/history/{correlation_id}", "/api/topology", "/api/loadtest", "/api/orders", "/api/orders/{id}", "/health", "/ready", "/health/deep", "/metrics", "/openapi.json"},
 		)
 		serverErrors <- server.ListenAndServe()
 	}()
@@ -76,8 +135,10 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		// Attempt graceful shutdown
-		if err := server.Shutdown(ctx); err != nil {
+		// Attempt graceful shutdown, then write the runs it let finish
+		err := server.Shutdown(ctx)
+		handler.FlushHistory()
+		if err != nil {
 			logger.Error("Graceful shutdown failed", "error", err.Error())
 			if err := server.Close(); err != nil {
 				logger.Error("Forced shutdown failed", "error", err.Error())
@@ -0,0 +1,196 @@
+// Command loadgen runs a load test through the frontend's /api/loadtest
+// endpoint and prints the report. The frontend generates the load itself, so
+// every run goes through its Envoy sidecar and SVID like a dashboard run.
+//
+//	go run ./cmd/loadgen -frontend http://localhost:8080 -rate 20 -concurrency 8 -duration 1m
+//
+// Interrupting loadgen cancels the load test and prints what ran so far.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// maxResponseBytes bounds report and problem bodies
+const maxResponseBytes = 4 << 20
+
+func main() {
+	frontendURL := flag.String("frontend", getEnv("FRONTEND_URL", "http://localhost:8080"), "frontend base URL")
+	rate := flag.Float64("rate", 10, "demo runs started per second")
+	concurrency := flag.Int("concurrency", 4, "maximum demo runs in flight")
+	duration := flag.Duration("duration", 30*time.Second, "length of the load test")
+	poll := flag.Duration("poll", 2*time.Second, "progress polling interval")
+	jsonOutput := flag.Bool("json", false, "print the final report as JSON")
+	flag.Parse()
+
+	client := &loadClient{
+		baseURL: strings.TrimSuffix(*frontendURL, "/"),
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	ctx := context.Background()
+	report, err := client.send(ctx, http.MethodPost, api.LoadTestRequest{
+		Rate:        *rate,
+		Concurrency: *concurrency,
+		Duration:    duration.String(),
+	}, http.StatusAccepted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start load test: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Load test started: %.1f/s, concurrency %d, for %s\n", *rate, *concurrency, duration)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	ticker := time.NewTicker(*poll)
+	defer ticker.Stop()
+	for report.State == api.LoadTestRunning {
+		select {
+		case <-interrupt:
+			fmt.Fprintln(os.Stderr, "Cancelling load test")
+			report, err = client.send(ctx, http.MethodDelete, nil, http.StatusOK)
+		case <-ticker.C:
+			report, err = client.send(ctx, http.MethodGet, nil, http.StatusOK)
+			if err == nil {
+				fmt.Fprintf(os.Stderr, "  %5.0fs  %6d runs  %5d failed  %5d skipped  p99 %.1f ms\n",
+					report.ElapsedMs/1000, report.Requests, report.Failures, report.Skipped, report.Latency.EndToEnd.P99)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read load test: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+// loadClient calls the frontend's /api/loadtest endpoint
+type loadClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// send calls /api/loadtest with an optional JSON body and decodes a report
+// from a response with status want. Problems are returned as *api.Error.
+func (c *loadClient) send(ctx context.Context, method string, body any, want int) (*api.LoadTestReport, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/loadtest", reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json, "+api.ProblemContentType)
+	req.Header.Set("User-Agent", "loadgen")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != want {
+		return nil, api.NewError(resp.StatusCode, resp.Header, data)
+	}
+
+	var report api.LoadTestReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%w: %v", api.ErrInvalidResponse, err)
+	}
+	return &report, nil
+}
+
+func printReport(out io.Writer, report *api.LoadTestReport) {
+	fmt.Fprintf(out, "\nLoad test %s after %.1fs\n", report.State, report.ElapsedMs/1000)
+	fmt.Fprintf(out, "  Target:   %.1f/s, concurrency %d, duration %s\n",
+		report.Request.Rate, report.Request.Concurrency, report.Request.Duration)
+	fmt.Fprintf(out, "  Achieved: %.1f/s, %d runs, %d succeeded, %d failed, %d skipped (all workers busy)\n",
+		report.AchievedRate, report.Requests, report.Successes, report.Failures, report.Skipped)
+
+	fmt.Fprintln(out, "\nLatency (ms)")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "hop\tcount\tp50\tp90\tp95\tp99\tmax\t")
+	for _, hop := range []struct {
+		name    string
+		summary api.LatencySummary
+	}{
+		{"end-to-end", report.Latency.EndToEnd},
+		{api.HopFrontendToBackend, report.Latency.FrontendToBackend},
+		{api.HopBackendToDatabase, report.Latency.BackendToDatabase},
+	} {
+		s := hop.summary
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t\n", hop.name, s.Count, s.P50, s.P90, s.P95, s.P99, s.Max)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nErrors by pattern")
+	if len(report.Errors) == 0 {
+		fmt.Fprintln(out, "  none")
+	} else {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  pattern\thop\treason\tcount\texample")
+		for _, e := range report.Errors {
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%s\n", e.Pattern, e.Hop, e.Reason, e.Count, e.Example)
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(out, "\nBackend connection pool")
+	switch wait := report.PoolWait; {
+	case wait != nil:
+		fmt.Fprintf(out, "  %d of %d runs waited for a connection, %.1f ms in total, %.2f ms mean, %.2f ms max\n",
+			wait.WaitCount, wait.Runs, wait.WaitDurationMS, wait.MeanWaitMS, wait.MaxWaitMS)
+	case report.PoolWaitError != "":
+		fmt.Fprintf(out, "  not available: %s\n", report.PoolWaitError)
+	}
+
+	fmt.Fprintln(out, "\nPer second (end-to-end ms)")
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "second\truns\tfailed\tp50\tp99\t")
+	for _, s := range report.Timeline {
+		fmt.Fprintf(w, "%d\t%d\t%d\t%.1f\t%.1f\t\n", s.Second, s.Requests, s.Failures, s.P50, s.P99)
+	}
+	w.Flush()
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"time"
+)
+
+// ConnectRetry controls how NewDB retries the initial connection while the
+// spiffe-helper sidecar writes certificates and PostgreSQL comes up
+type ConnectRetry struct {
+	Deadline       time.Duration
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// NewConnectRetryFromEnv creates connection retry settings from environment variables
+func NewConnectRetryFromEnv() (ConnectRetry, error) {
+	retry := ConnectRetry{
+		Deadline:       getEnvAsDuration("DB_CONNECT_DEADLINE", 2*time.Minute),
+		InitialBackoff: getEnvAsDuration("DB_CONNECT_INITIAL_BACKOFF", 500*time.Millisecond),
+		MaxBackoff:     getEnvAsDuration("DB_CONNECT_MAX_BACKOFF", 10*time.Second),
+	}
+
+	// A non-positive deadline allows no attempt at all, and non-positive
+	// backoffs retry in a tight loop
+	if retry.Deadline <= 0 {
+		return ConnectRetry{}, errors.New("DB_CONNECT_DEADLINE must be positive")
+	}
+	if retry.InitialBackoff <= 0 {
+		return ConnectRetry{}, errors.New("DB_CONNECT_INITIAL_BACKOFF must be positive")
+	}
+	if retry.MaxBackoff < retry.InitialBackoff {
+		return ConnectRetry{}, errors.New("DB_CONNECT_MAX_BACKOFF must be at least DB_CONNECT_INITIAL_BACKOFF")
+	}
+	return retry, nil
+}
+
+// backoff returns the delay before the given retry (1-based): exponential
+// growth capped at MaxBackoff, with jitter over the upper half so replicas
+// starting together do not retry in lockstep
+func (c ConnectRetry) backoff(retry int) time.Duration {
+	d := c.InitialBackoff
+	for i := 1; i < retry && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, c.MaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// connectWithRetry waits for the SPIFFE certificate files to be usable, then
+// pings the database until it succeeds or the retry deadline passes
+func connectWithRetry(ctx context.Context, db *sql.DB, config *DBConfig, spiffeID string, logger *Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, config.ConnectRetry.Deadline)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		logger.LogConnectionAttempt(ctx, PatternSpiffeHelper, config.Host, spiffeID)
+
+		err := checkCertFiles(config)
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		logger.LogConnectionFailure(ctx, PatternSpiffeHelper, config.Host, spiffeID, err)
+
+		delay := config.ConnectRetry.backoff(attempt)
+		logger.Info("Retrying database connection",
+			"attempt", attempt,
+			"backoff", delay,
+			"pattern", PatternSpiffeHelper,
+		)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// checkCertFiles verifies the spiffe-helper output exists and parses. On pod
+// start the sidecar may not have written it yet.
+func checkCertFiles(config *DBConfig) error {
+	for _, path := range []string{config.SSLCert, config.SSLKey, config.SSLRootCA} {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("certificate file not ready: %w", err)
+		}
+	}
+
+	if _, err := tls.LoadX509KeyPair(config.SSLCert, config.SSLKey); err != nil {
+		return fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	bundle, err := os.ReadFile(config.SSLRootCA)
+	if err != nil {
+		return fmt.Errorf("failed to read trust bundle: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+		return fmt.Errorf("trust bundle %s contains no certificates", config.SSLRootCA)
+	}
+
+	return nil
+}
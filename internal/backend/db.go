@@ -3,52 +3,140 @@ package backend
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/lib/pq" // PostgreSQL driver
+
+	"github.com/example/spire-workload-demo/internal/reqctx"
 )
 
 // DBConfig holds database connection configuration
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	// Storage driver: "postgres" (default) or "sqlite" for offline demos
+	Driver     string
+	SQLitePath string
+	Host       string
+	Port       string
+	User       string
+	Password   string
+	DBName     string
 	// Connection pool settings (FR-020)
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// Pool statistics sampling; waits above the threshold between samples are logged
+	PoolSampleInterval    time.Duration
+	PoolWaitWarnThreshold time.Duration
 	// SPIFFE certificate paths (Pattern 2: spiffe-helper)
 	SSLCert   string
 	SSLKey    string
 	SSLRootCA string
+	// Server verification: verify-ca, verify-full or verify-spiffe. disable
+	// and require are refused unless AllowInsecureSSL is set.
+	SSLMode          string
+	ServerSPIFFEID   string
+	AllowInsecureSSL bool
+	// Apply schema migrations on startup
+	AutoMigrate bool
+	// Row-level security: scope request queries to the caller's SPIFFE ID
+	// ("spiffe_id") or to every workload in its namespace ("namespace")
+	RLSEnabled bool
+	RLSScope   string
+	// Optional read replicas for read-only queries
+	ReplicaRouting ReplicaRouting
+	// Per-operation query timeouts, keyed by Op* constants
+	QueryTimeouts map[string]time.Duration
+	// Circuit breaker and per-endpoint bulkhead around request queries
+	Resilience ResilienceConfig
+	// Query timing and slow query logging
+	QueryLog QueryLogConfig
+	// Startup connection retry
+	ConnectRetry ConnectRetry
+	// Per-identity Postgres roles for request-scoped transactions. The
+	// connecting user is a superuser, so RLS only applies after SET ROLE.
+	RoleMapping *RoleMapping
+	// Client certificates for negative-path demo scenarios
+	ScenarioCertDir string
 }
 
+// RLS scopes for DBConfig.RLSScope
+const (
+	RLSScopeSPIFFEID  = "spiffe_id"
+	RLSScopeNamespace = "namespace"
+)
+
 // NewDBConfigFromEnv creates database configuration from environment variables
-func NewDBConfigFromEnv() *DBConfig {
+func NewDBConfigFromEnv() (*DBConfig, error) {
 	// Parse connection pool settings with defaults (FR-020)
 	maxOpenConns := getEnvAsInt("DB_MAX_OPEN_CONNS", 10)
 	maxIdleConns := getEnvAsInt("DB_MAX_IDLE_CONNS", 5)
 	connMaxLifetime := getEnvAsDuration("DB_CONN_MAX_LIFETIME", 2*time.Minute)
 
-	return &DBConfig{
-		Host:            getEnv("DB_HOST", "postgres.demo.svc.cluster.local"),
-		Port:            getEnv("DB_PORT", "5432"),
-		User:            getEnv("DB_USER", "postgres"),
-		Password:        getEnv("DB_PASSWORD", ""),
-		DBName:          getEnv("DB_NAME", "demodb"),
-		MaxOpenConns:    maxOpenConns,
-		MaxIdleConns:    maxIdleConns,
-		ConnMaxLifetime: connMaxLifetime,
+	roleMapping, err := NewRoleMappingFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	connectRetry, err := NewConnectRetryFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	sslMode := getEnv("DB_SSLMODE", SSLModeVerifySPIFFE)
+	serverSPIFFEID := getEnv("DB_SERVER_SPIFFE_ID", "spiffe://example.org/ns/demo/sa/postgres")
+	allowInsecureSSL := getEnvAsBool("DB_ALLOW_INSECURE_SSLMODE", false)
+	if err := validateSSLMode(sslMode, serverSPIFFEID, allowInsecureSSL); err != nil {
+		return nil, err
+	}
+
+	config := &DBConfig{
+		Driver:                getEnv("DB_DRIVER", DriverPostgres),
+		SQLitePath:            getEnv("DB_SQLITE_PATH", "demo.db"),
+		Host:                  getEnv("DB_HOST", "postgres.demo.svc.cluster.local"),
+		Port:                  getEnv("DB_PORT", "5432"),
+		User:                  getEnv("DB_USER", "postgres"),
+		Password:              getEnv("DB_PASSWORD", ""),
+		DBName:                getEnv("DB_NAME", "demodb"),
+		MaxOpenConns:          maxOpenConns,
+		MaxIdleConns:          maxIdleConns,
+		ConnMaxLifetime:       connMaxLifetime,
+		PoolSampleInterval:    getEnvAsDuration("DB_POOL_SAMPLE_INTERVAL", 10*time.Second),
+		PoolWaitWarnThreshold: getEnvAsDuration("DB_POOL_WAIT_WARN_THRESHOLD", 100*time.Millisecond),
 		// SPIFFE certificates written by spiffe-helper sidecar
-		SSLCert:   getEnv("SSL_CERT", "/spiffe-certs/svid.pem"),
-		SSLKey:    getEnv("SSL_KEY", "/spiffe-certs/svid_key.pem"),
-		SSLRootCA: getEnv("SSL_ROOT_CA", "/spiffe-certs/svid_bundle.pem"),
+		SSLCert:          getEnv("SSL_CERT", "/spiffe-certs/svid.pem"),
+		SSLKey:           getEnv("SSL_KEY", "/spiffe-certs/svid_key.pem"),
+		SSLRootCA:        getEnv("SSL_ROOT_CA", "/spiffe-certs/svid_bundle.pem"),
+		SSLMode:          sslMode,
+		ServerSPIFFEID:   serverSPIFFEID,
+		AllowInsecureSSL: allowInsecureSSL,
+		AutoMigrate:      getEnvAsBool("DB_AUTO_MIGRATE", true),
+		RLSEnabled:       getEnvAsBool("DB_RLS_ENABLED", true),
+		RLSScope:         getEnv("DB_RLS_SCOPE", RLSScopeSPIFFEID),
+		ConnectRetry:     connectRetry,
+		QueryTimeouts:    newQueryTimeoutsFromEnv(OpGetAllOrders, OpGetOrder, OpCreateOrder, OpUpdateOrder, OpHealthCheck),
+		Resilience:       NewResilienceConfigFromEnv(),
+		RoleMapping:      roleMapping,
+		ScenarioCertDir:  getEnv("DB_SCENARIO_CERT_DIR", "/run/scenario-certs"),
+	}
+	config.ReplicaRouting = NewReplicaRoutingFromEnv(config)
+
+	// Without a role switch every query runs as the connecting superuser,
+	// which RLS does not apply to
+	if config.Driver != DriverSQLite && config.RLSEnabled && !config.RoleMapping.Enabled {
+		return nil, errors.New("DB_RLS_ENABLED=true requires DB_ROLE_MAPPING_ENABLED=true: the connecting user bypasses row-level security")
 	}
+	if config.PoolSampleInterval <= 0 {
+		return nil, errors.New("DB_POOL_SAMPLE_INTERVAL must be positive")
+	}
+	if len(config.ReplicaRouting.Replicas) > 0 && config.ReplicaRouting.HealthInterval <= 0 {
+		return nil, errors.New("DB_REPLICA_HEALTH_INTERVAL must be positive")
+	}
+
+	return config, nil
 }
 
 // DB wraps sql.DB with structured logging
@@ -56,96 +144,295 @@ type DB struct {
 	*sql.DB
 	logger *Logger
 	config *DBConfig
+	pool   *PoolMonitor
+	// Read replicas for read-only request queries; may be empty
+	replicas *replicaSet
+	breaker  *circuitBreaker
+	bulkhead *bulkhead
 }
 
-// NewDB creates a new database connection with client certificate authentication (Pattern 2)
+// NewDB creates a new database connection with client certificate authentication (Pattern 2).
+// It blocks until the certificates are usable and PostgreSQL answers, retrying
+// with backoff until config.ConnectRetry.Deadline.
 func NewDB(ctx context.Context, config *DBConfig, logger *Logger) (*DB, error) {
+	if config.Driver == DriverSQLite {
+		return newSQLiteDB(ctx, config, logger)
+	}
+
 	spiffeID := getEnv("SPIFFE_ID", "spiffe://example.org/ns/demo/sa/backend")
-	
-	logger.LogConnectionAttempt(ctx, PatternSpiffeHelper, config.Host, spiffeID)
+
+	// Open database connection
+	db, err := openPool(config, config.Host, config.Port, config.SSLCert, config.SSLKey, config.SSLRootCA)
+	if err != nil {
+		logger.LogConnectionFailure(ctx, PatternSpiffeHelper, config.Host, spiffeID, err)
+		return nil, err
+	}
+
+	logger.Info("Connection pool configured",
+		"max_open_conns", config.MaxOpenConns,
+		"max_idle_conns", config.MaxIdleConns,
+		"conn_max_lifetime", config.ConnMaxLifetime,
+	)
+
+	// Test the connection, waiting for spiffe-helper and PostgreSQL
+	if err := connectWithRetry(ctx, db, config, spiffeID, logger); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Log successful connection with Pattern 2 (spiffe-helper)
+	logger.LogConnectionSuccess(ctx, PatternSpiffeHelper, config.Host, spiffeID, config.ServerSPIFFEID)
+
+	// Read replicas join rotation once their health checks pass
+	replicas, err := newReplicaSet(config, logger)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &DB{
+		DB:       db,
+		logger:   logger,
+		config:   config,
+		pool:     newPoolMonitor(db, config, logger),
+		replicas: replicas,
+		breaker:  newCircuitBreaker(config.Resilience, logger),
+		bulkhead: newBulkhead(config.Resilience),
+	}, nil
+}
+
+// openPool opens a connection pool using SSL client certificate authentication
+func openPool(config *DBConfig, host, port, sslCert, sslKey, sslRootCA string) (*sql.DB, error) {
+	if config.SSLMode == SSLModeVerifySPIFFE {
+		return openSPIFFEPool(config, host, port, sslCert, sslKey, sslRootCA)
+	}
 
 	// Build connection string with SSL client certificate authentication
 	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=require sslcert=%s sslkey=%s sslrootcert=%s",
-		config.Host,
-		config.Port,
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s sslcert=%s sslkey=%s sslrootcert=%s",
+		host,
+		port,
 		config.User,
 		config.Password,
 		config.DBName,
-		config.SSLCert,
-		config.SSLKey,
-		config.SSLRootCA,
+		config.SSLMode,
+		sslCert,
+		sslKey,
+		sslRootCA,
 	)
 
-	// Open database connection
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		logger.LogConnectionFailure(ctx, PatternSpiffeHelper, config.Host, spiffeID, err)
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
+	configurePool(db, config)
+
+	return db, nil
+}
+
+// openSPIFFEPool opens a pool whose connections negotiate TLS in
+// spiffeDialer, verifying the server's SPIFFE ID
+func openSPIFFEPool(config *DBConfig, host, port, sslCert, sslKey, sslRootCA string) (*sql.DB, error) {
+	// TLS is already established by the dialer, so lib/pq must not negotiate it again
+	connStr := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		host,
+		port,
+		config.User,
+		config.Password,
+		config.DBName,
+	)
+
+	connector, err := pq.NewConnector(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database connector: %w", err)
+	}
+	connector.Dialer(&spiffeDialer{
+		sslCert:        sslCert,
+		sslKey:         sslKey,
+		sslRootCA:      sslRootCA,
+		serverSPIFFEID: config.ServerSPIFFEID,
+	})
+
+	db := sql.OpenDB(connector)
+	configurePool(db, config)
 
+	return db, nil
+}
+
+func configurePool(db *sql.DB, config *DBConfig) {
 	// Configure connection pool (FR-020)
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+}
 
-	logger.Info("Connection pool configured",
-		"max_open_conns", config.MaxOpenConns,
-		"max_idle_conns", config.MaxIdleConns,
-		"conn_max_lifetime", config.ConnMaxLifetime,
-	)
+// Close closes the replica pools and the primary pool
+func (db *DB) Close() error {
+	db.replicas.Close()
+	return db.DB.Close()
+}
 
-	// Test the connection
-	if err := db.PingContext(ctx); err != nil {
-		logger.LogConnectionFailure(ctx, PatternSpiffeHelper, config.Host, spiffeID, err)
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+// MonitorReplicas health-checks read replicas until ctx is cancelled
+func (db *DB) MonitorReplicas(ctx context.Context) {
+	db.replicas.Run(ctx)
+}
+
+// reader returns the pool and target name for a read-only query: a healthy
+// replica unless the request asked to read its own writes
+func (db *DB) reader(ctx context.Context) (*sql.DB, string) {
+	if !ReadYourWrites(ctx) {
+		if r := db.replicas.pick(); r != nil {
+			return r.db, r.config.Name
+		}
 	}
+	return db.DB, "primary"
+}
 
-	// Log successful connection with Pattern 2 (spiffe-helper)
-	peerSPIFFEID := "spiffe://example.org/ns/demo/sa/postgres"
-	logger.LogConnectionSuccess(ctx, PatternSpiffeHelper, config.Host, spiffeID, peerSPIFFEID)
+// requestTx runs fn in a transaction scoped to the caller's identity. The
+// transaction switches to the caller's mapped role and, when RLS is enabled,
+// exposes the caller's SPIFFE ID as the transaction-local setting
+// app.spiffe_id. Both revert on commit or rollback, so pooled connections
+// stay clean.
+//
+// Read-only transactions are routed to a healthy replica when one is available.
+// The work runs inside the endpoint's bulkhead and the circuit breaker.
+func (db *DB) requestTx(ctx context.Context, readOnly bool, fn func(tx *sql.Tx) error) error {
+	return db.guard(ctx, func() error {
+		return db.runRequestTx(ctx, readOnly, fn)
+	})
+}
 
-	return &DB{
-		DB:     db,
-		logger: logger,
-		config: config,
-	}, nil
+func (db *DB) runRequestTx(ctx context.Context, readOnly bool, fn func(tx *sql.Tx) error) error {
+	caller := CallerSPIFFEID(ctx)
+
+	var role string
+	if db.config.RoleMapping.Enabled && !db.isSQLite() {
+		var err error
+		if role, err = db.config.RoleMapping.Resolve(caller); err != nil {
+			db.logger.ErrorContext(ctx, "Database role mapping denied caller", "caller_spiffe_id", caller)
+			return err
+		}
+	}
+
+	pool, target := db.DB, "primary"
+	if readOnly {
+		pool, target = db.reader(ctx)
+	}
+
+	// A wait shows up in the pool's WaitCount; another request starting to
+	// wait at the same moment can only add a free connection's near-zero
+	// acquisition time
+	waits := pool.Stats().WaitCount
+	start := time.Now()
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction on %s: %w", target, err)
+	}
+	defer conn.Close()
+	if pool.Stats().WaitCount > waits {
+		addPoolWait(ctx, time.Since(start))
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: readOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction on %s: %w", target, err)
+	}
+	defer tx.Rollback()
+
+	if role != "" {
+		if _, err := db.exec(ctx, tx, setRoleStatement(role)); err != nil {
+			return fmt.Errorf("failed to set role %s: %w", role, err)
+		}
+	}
+
+	// Tag the session so PostgreSQL logs (%a) and pg_stat_activity can be
+	// joined with application logs; reverts at transaction end
+	if id := reqctx.CorrelationID(ctx); id != "" && !db.isSQLite() {
+		if _, err := db.exec(ctx, tx,
+			`SELECT set_config('application_name', $1, true)`,
+			applicationName(id),
+		); err != nil {
+			return fmt.Errorf("failed to set application_name: %w", err)
+		}
+	}
+
+	if db.config.RLSEnabled && !db.isSQLite() {
+		if _, err := db.exec(ctx, tx,
+			`SELECT set_config('app.spiffe_id', $1, true), set_config('app.rls_scope', $2, true)`,
+			caller, db.config.RLSScope,
+		); err != nil {
+			return fmt.Errorf("failed to set caller identity: %w", err)
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// applicationName returns the application_name for a request transaction.
+// PostgreSQL truncates it to 63 bytes.
+func applicationName(correlationID string) string {
+	return "backend " + correlationID
+}
+
+// MonitorPool samples connection pool statistics until ctx is cancelled
+func (db *DB) MonitorPool(ctx context.Context) {
+	db.pool.Run(ctx)
 }
 
-// GetAllOrders retrieves all orders from the database
+// GetAllOrders retrieves all orders visible to the caller
 func (db *DB) GetAllOrders(ctx context.Context) ([]Order, error) {
-	query := `SELECT id, description, status, created_at FROM orders ORDER BY created_at DESC`
+	ctx, cancel := db.withQueryTimeout(ctx, OpGetAllOrders)
+	defer cancel()
 
-	rows, err := db.QueryContext(ctx, query)
+	var orders []Order
+	err := db.requestTx(ctx, true, func(tx *sql.Tx) error {
+		var err error
+		orders, err = db.queryOrders(ctx, tx)
+		return err
+	})
 	if err != nil {
-		db.logger.Error("Failed to query orders", "error", err)
-		return nil, fmt.Errorf("failed to query orders: %w", err)
+		return nil, timeoutCause(ctx, err)
 	}
-	defer rows.Close()
+
+	db.logger.InfoContext(ctx, "Retrieved orders",
+		"count", len(orders),
+		"pattern", db.Pattern(),
+		"caller_spiffe_id", CallerSPIFFEID(ctx),
+	)
+	return orders, nil
+}
+
+func (db *DB) queryOrders(ctx context.Context, tx *sql.Tx) ([]Order, error) {
+	query := `SELECT id, description, status, created_at FROM orders ORDER BY created_at DESC`
 
 	var orders []Order
-	for rows.Next() {
+	err := db.queryRows(ctx, tx, query, nil, func(rows *sql.Rows) error {
 		var order Order
 		if err := rows.Scan(&order.ID, &order.Description, &order.Status, &order.CreatedAt); err != nil {
-			db.logger.Error("Failed to scan order row", "error", err)
-			return nil, fmt.Errorf("failed to scan order: %w", err)
+			return fmt.Errorf("failed to scan order: %w", err)
 		}
 		orders = append(orders, order)
+		return nil
+	})
+	if err != nil {
+		db.logger.ErrorContext(ctx, "Failed to query orders", "error", err)
+		return nil, fmt.Errorf("failed to query orders: %w", err)
 	}
 
-	if err := rows.Err(); err != nil {
-		db.logger.Error("Error iterating order rows", "error", err)
-		return nil, fmt.Errorf("error iterating orders: %w", err)
-	}
-
-	db.logger.Info("Retrieved orders", "count", len(orders), "pattern", PatternSpiffeHelper)
 	return orders, nil
 }
 
 // HealthCheck verifies database connectivity
 func (db *DB) HealthCheck(ctx context.Context) error {
+	ctx, cancel := db.withQueryTimeout(ctx, OpHealthCheck)
+	defer cancel()
+
 	if err := db.PingContext(ctx); err != nil {
-		return fmt.Errorf("database health check failed: %w", err)
+		return fmt.Errorf("database health check failed: %w", timeoutCause(ctx, err))
 	}
 	return nil
 }
@@ -182,3 +469,27 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return value
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,129 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// Deadline propagation headers shared with the frontend
+const (
+	HeaderRequestBudget = api.HeaderRequestBudget
+	HeaderTimeoutHop    = api.HeaderTimeoutHop
+)
+
+// Hops reported when a deadline is exceeded
+const (
+	HopFrontendToBackend = api.HopFrontendToBackend
+	HopBackendToDatabase = api.HopBackendToDatabase
+)
+
+// Database operations with individually configurable timeouts
+const (
+	OpGetAllOrders = "get_all_orders"
+	OpGetOrder     = "get_order"
+	OpCreateOrder  = "create_order"
+	OpUpdateOrder  = "update_order"
+	OpHealthCheck  = "health_check"
+)
+
+var (
+	// ErrQueryTimeout means a database operation exceeded its own timeout
+	ErrQueryTimeout = errors.New("database query timeout")
+	// ErrBudgetExhausted means the deadline propagated by the caller passed
+	ErrBudgetExhausted = errors.New("caller deadline budget exhausted")
+)
+
+// newQueryTimeoutsFromEnv reads DB_QUERY_TIMEOUT as the default and
+// DB_QUERY_TIMEOUT_<OPERATION> overrides, e.g. DB_QUERY_TIMEOUT_GET_ALL_ORDERS
+func newQueryTimeoutsFromEnv(ops ...string) map[string]time.Duration {
+	fallback := getEnvAsDuration("DB_QUERY_TIMEOUT", 5*time.Second)
+	timeouts := make(map[string]time.Duration, len(ops))
+	for _, op := range ops {
+		timeouts[op] = getEnvAsDuration("DB_QUERY_TIMEOUT_"+strings.ToUpper(op), fallback)
+	}
+	return timeouts
+}
+
+// withQueryTimeout bounds a database operation by its configured timeout.
+// Whichever of the operation timeout and the caller's deadline fires first
+// is recorded as the context cause.
+func (db *DB) withQueryTimeout(ctx context.Context, op string) (context.Context, context.CancelFunc) {
+	timeout, ok := db.config.QueryTimeouts[op]
+	if !ok || timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeoutCause(ctx, timeout,
+		fmt.Errorf("%w: %s exceeded %s", ErrQueryTimeout, op, timeout))
+}
+
+// timeoutCause wraps err with the context's cancellation cause so callers
+// can tell which deadline fired with errors.Is. An error already carrying
+// the cause is returned as is.
+func timeoutCause(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+	cause := context.Cause(ctx)
+	if errors.Is(err, cause) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
+// timeoutHop returns the hop that ran out of budget, or "" if err is not a
+// deadline error
+func timeoutHop(err error) string {
+	switch {
+	case errors.Is(err, ErrBudgetExhausted):
+		return HopFrontendToBackend
+	case errors.Is(err, ErrQueryTimeout):
+		return HopBackendToDatabase
+	default:
+		return ""
+	}
+}
+
+// writeTimeout logs which hop ran out of budget and responds 504. It returns
+// false if err is not a deadline error.
+func (h *Handler) writeTimeout(w http.ResponseWriter, r *http.Request, err error) bool {
+	hop := timeoutHop(err)
+	if hop == "" {
+		return false
+	}
+
+	h.logger.ErrorContext(r.Context(), "Deadline exceeded",
+		"event", EventDeadlineExceeded,
+		"hop", hop,
+		"path", r.URL.Path,
+		"error", err,
+	)
+	api.WriteProblem(w, r, api.NewProblem(api.ProblemDeadlineExceeded, http.StatusGatewayTimeout,
+		"Deadline exceeded on "+hop).WithHop(hop))
+	return true
+}
+
+// DeadlineMiddleware applies the caller's propagated budget to the request
+// context so the backend gives up once the frontend would have
+func (h *Handler) DeadlineMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		budgetMS, err := strconv.ParseInt(r.Header.Get(HeaderRequestBudget), 10, 64)
+		if err != nil || budgetMS <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		budget := time.Duration(budgetMS) * time.Millisecond
+		ctx, cancel := context.WithTimeoutCause(r.Context(), budget,
+			fmt.Errorf("%w: %s", ErrBudgetExhausted, budget))
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -2,40 +2,112 @@ package backend
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"sync/atomic"
 	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/ratelimit"
 )
 
 // Handler provides HTTP handlers for the backend API
 type Handler struct {
-	db     *DB
+	// db is nil until the startup connection succeeds (see SetDB)
+	db     atomic.Pointer[DB]
+	config *DBConfig
 	logger *Logger
+	// Per-route caller limits, loaded by StartRateLimiter before serving
+	rateLimits *ratelimit.Service
 }
 
-// NewHandler creates a new HTTP handler
-func NewHandler(db *DB, logger *Logger) *Handler {
+// NewHandler creates a new HTTP handler. The server can start before the
+// database is reachable; call SetDB once NewDB returns.
+func NewHandler(config *DBConfig, logger *Logger) *Handler {
 	return &Handler{
-		db:     db,
-		logger: logger,
+		config:     config,
+		logger:     logger,
+		rateLimits: newRateLimits(logger),
+	}
+}
+
+// SetDB attaches the connected database and marks the handler ready
+func (h *Handler) SetDB(db *DB) {
+	h.db.Store(db)
+}
+
+// Close closes the database if it has been connected
+func (h *Handler) Close() error {
+	if db := h.db.Load(); db != nil {
+		return db.Close()
 	}
+	return nil
 }
 
-// HealthHandler handles GET /health requests
+// database returns the connected database, or writes 503 and returns nil if
+// the startup connection has not completed yet
+func (h *Handler) database(w http.ResponseWriter, r *http.Request) *DB {
+	db := h.db.Load()
+	if db == nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemDatabaseUnavailable, http.StatusServiceUnavailable,
+			"Database connection not established yet").WithHop(HopBackendToDatabase))
+	}
+	return db
+}
+
+// HealthHandler handles GET /health requests (liveness). While the startup
+// connection is still retrying the process is alive, so it reports starting.
 func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	db := h.db.Load()
+	if db == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(HealthResponse{
+			Component: "backend",
+			Status:    "starting",
+		})
+		return
+	}
+
 	// Check database health
-	if err := h.db.HealthCheck(ctx); err != nil {
-		h.logger.Error("Health check failed", "error", err)
-		http.Error(w, "Database unhealthy", http.StatusServiceUnavailable)
+	start := time.Now()
+	if err := db.HealthCheck(ctx); err != nil {
+		h.logger.ErrorContext(ctx, "Health check failed", "error", err)
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemDatabaseUnavailable, http.StatusServiceUnavailable,
+			"Database health check failed").WithHop(HopBackendToDatabase))
 		return
 	}
+	latencyMs := float64(time.Since(start).Microseconds()) / 1000
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
-		"component": "backend",
+	json.NewEncoder(w).Encode(HealthResponse{
+		Component:         "backend",
+		Status:            "healthy",
+		DatabaseLatencyMs: latencyMs,
+	})
+}
+
+// ReadyHandler handles GET /ready requests (readiness). It reports not-ready
+// until the database is connected and reachable.
+func (h *Handler) ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
+	if err := db.HealthCheck(r.Context()); err != nil {
+		h.logger.ErrorContext(r.Context(), "Readiness check failed", "error", err)
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemDatabaseUnavailable, http.StatusServiceUnavailable,
+			"Database unreachable").WithHop(HopBackendToDatabase))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthResponse{
+		Component: "backend",
+		Status:    "ready",
 	})
 }
 
@@ -43,11 +115,14 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) OrdersHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
 	// Retrieve orders from database (Pattern 2: spiffe-helper)
-	orders, err := h.db.GetAllOrders(ctx)
-	if err != nil {
-		h.logger.Error("Failed to retrieve orders", "error", err)
-		http.Error(w, "Failed to retrieve orders", http.StatusInternalServerError)
+	orders, err := db.GetAllOrders(ctx)
+	if h.writeOrderError(w, r, err, "retrieve orders") {
 		return
 	}
 
@@ -57,6 +132,11 @@ func (h *Handler) OrdersHandler(w http.ResponseWriter, r *http.Request) {
 
 // DemoHandler handles GET /api/demo requests - full demo flow
 func (h *Handler) DemoHandler(w http.ResponseWriter, r *http.Request) {
+	if scenario := r.URL.Query().Get("scenario"); scenario != "" {
+		h.scenarioDemo(w, r, scenario)
+		return
+	}
+
 	ctx := r.Context()
 	spiffeID := getEnv("SPIFFE_ID", "spiffe://example.org/ns/demo/sa/backend")
 
@@ -66,7 +146,10 @@ func (h *Handler) DemoHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Pattern 1: Frontend-to-Backend already verified by Envoy RBAC
 	// The fact that we reached this handler means the frontend's SVID was validated
-	frontendSPIFFEID := "spiffe://example.org/ns/demo/sa/frontend"
+	frontendSPIFFEID := CallerSPIFFEID(ctx)
+	if frontendSPIFFEID == "" {
+		frontendSPIFFEID = "spiffe://example.org/ns/demo/sa/frontend"
+	}
 	h.logger.LogEvent(ctx, PatternEnvoySDS, EventConnectionSuccess, spiffeID, frontendSPIFFEID, 
 		"Frontend-to-backend connection validated by Envoy RBAC")
 	
@@ -77,23 +160,68 @@ func (h *Handler) DemoHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Pattern 2: Backend-to-Database with spiffe-helper client certificates
-	orders, err := h.db.GetAllOrders(ctx)
+	pattern := PatternSpiffeHelper
+	if h.config.Driver == DriverSQLite {
+		pattern = PatternLocalSQLite
+	}
+
+	var orders []Order
+	var err error
+	var circuitState string
+	var latencyMs, poolWaitMs float64
+	if db := h.db.Load(); db != nil {
+		var poolWait time.Duration
+		start := time.Now()
+		orders, err = db.GetAllOrders(withPoolWait(ctx, &poolWait))
+		latencyMs = float64(time.Since(start).Microseconds()) / 1000
+		poolWaitMs = float64(poolWait.Microseconds()) / 1000
+		circuitState = db.CircuitState()
+	} else {
+		err = errors.New("database connection not established yet")
+	}
+	if h.writeTimeout(w, r, err) {
+		return
+	}
+	target, store := h.config.Host, "PostgreSQL"
+	if pattern == PatternLocalSQLite {
+		target, store = h.config.SQLitePath, "the local SQLite file"
+	}
 	if err != nil {
-		h.logger.Error("Backend-to-database connection failed", "error", err, "pattern", PatternSpiffeHelper)
+		h.logger.LogConnectionFailure(ctx, pattern, target, spiffeID, err)
 		result.BackendToDatabase = ConnectionStatus{
-			Success: false,
-			Message: "Failed to connect to PostgreSQL: " + err.Error(),
-			Pattern: PatternSpiffeHelper,
+			Success:      false,
+			Message:      "Failed to connect to " + store + ": " + err.Error(),
+			Pattern:      pattern,
+			CircuitState: circuitState,
+			LatencyMs:    latencyMs,
+			PoolWaitMs:   poolWaitMs,
 		}
+		if errors.Is(err, ErrCircuitOpen) {
+			result.BackendToDatabase.Message = "Circuit breaker open, " + store + " not contacted: " + err.Error()
+		}
+	} else if pattern == PatternLocalSQLite {
+		// No peer SPIFFE ID: nothing was authenticated on this hop
+		h.logger.LogConnectionSuccess(ctx, pattern, target, spiffeID, "")
+
+		result.BackendToDatabase = ConnectionStatus{
+			Success:      true,
+			Message:      "Local SQLite file: no network connection and no authentication (offline demo mode)",
+			Pattern:      pattern,
+			CircuitState: circuitState,
+			LatencyMs:    latencyMs,
+			PoolWaitMs:   poolWaitMs,
+		}
+		result.Orders = orders
 	} else {
-		postgresSPIFFEID := "spiffe://example.org/ns/demo/sa/postgres"
-		h.logger.LogEvent(ctx, PatternSpiffeHelper, EventConnectionSuccess, spiffeID, postgresSPIFFEID,
-			"Backend-to-database connection successful with client certificate authentication")
+		h.logger.LogConnectionSuccess(ctx, PatternSpiffeHelper, target, spiffeID, h.config.ServerSPIFFEID)
 		
 		result.BackendToDatabase = ConnectionStatus{
-			Success: true,
-			Message: "PostgreSQL verified backend SPIFFE ID from client certificate",
-			Pattern: PatternSpiffeHelper,
+			Success:      true,
+			Message:      "PostgreSQL verified backend SPIFFE ID from client certificate",
+			Pattern:      PatternSpiffeHelper,
+			CircuitState: circuitState,
+			LatencyMs:    latencyMs,
+			PoolWaitMs:   poolWaitMs,
 		}
 		result.Orders = orders
 	}
@@ -102,12 +230,19 @@ func (h *Handler) DemoHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(result)
 }
 
+// TopologyHandler handles GET /api/topology requests: the connections the
+// backend has logged, inbound from its callers and outbound to the database
+func (h *Handler) TopologyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.logger.Topology())
+}
+
 // LoggingMiddleware logs HTTP requests with structured logging
 func (h *Handler) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		
-		h.logger.Info("HTTP request",
+		h.logger.InfoContext(r.Context(), "HTTP request",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"remote_addr", r.RemoteAddr,
@@ -115,7 +250,7 @@ func (h *Handler) LoggingMiddleware(next http.Handler) http.Handler {
 
 		next.ServeHTTP(w, r)
 
-		h.logger.Info("HTTP response",
+		h.logger.InfoContext(r.Context(), "HTTP response",
 			"method", r.Method,
 			"path", r.URL.Path,
 			"duration_ms", time.Since(start).Milliseconds(),
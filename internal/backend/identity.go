@@ -0,0 +1,97 @@
+package backend
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// HeaderXFCC carries the peer certificate details Envoy forwards after
+// terminating mTLS (forward_client_cert_details: SANITIZE_SET)
+const HeaderXFCC = "X-Forwarded-Client-Cert"
+
+type callerSPIFFEIDKey struct{}
+
+// WithCallerSPIFFEID returns a context carrying the caller's SPIFFE ID
+func WithCallerSPIFFEID(ctx context.Context, spiffeID string) context.Context {
+	return context.WithValue(ctx, callerSPIFFEIDKey{}, spiffeID)
+}
+
+// CallerSPIFFEID returns the caller's SPIFFE ID, or "" if the request did not
+// arrive through Envoy with a verified client certificate
+func CallerSPIFFEID(ctx context.Context) string {
+	spiffeID, _ := ctx.Value(callerSPIFFEIDKey{}).(string)
+	return spiffeID
+}
+
+// IdentityMiddleware extracts the caller's SPIFFE ID from the XFCC header set
+// by the Envoy sidecar. Envoy sanitizes any client-supplied XFCC header, so
+// the value reflects the certificate Envoy actually verified. The header is
+// only trusted on connections from loopback, i.e. from the sidecar: the app
+// port is reachable on the pod IP for probes, and anything connecting there
+// directly could claim any identity.
+func (h *Handler) IdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !fromLoopback(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if spiffeID := parseXFCCURI(r.Header.Get(HeaderXFCC)); spiffeID != "" {
+			r = r.WithContext(WithCallerSPIFFEID(r.Context(), spiffeID))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// fromLoopback reports whether the request's connection came from the same
+// pod network namespace, where only the Envoy sidecar runs
+func fromLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// parseXFCCURI returns the spiffe:// URI from the last XFCC element, which is
+// the one appended by the nearest proxy
+func parseXFCCURI(header string) string {
+	if header == "" {
+		return ""
+	}
+
+	elements := splitUnquoted(header, ',')
+	for _, pair := range splitUnquoted(elements[len(elements)-1], ';') {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || !strings.EqualFold(strings.TrimSpace(key), "URI") {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if strings.HasPrefix(value, "spiffe://") {
+			return value
+		}
+	}
+	return ""
+}
+
+// splitUnquoted splits s on sep, ignoring separators inside double quotes
+func splitUnquoted(s string, sep rune) []string {
+	var parts []string
+	var current strings.Builder
+	quoted := false
+	for _, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+			current.WriteRune(c)
+		case c == sep && !quoted:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	return append(parts, current.String())
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"log/slog"
 	"os"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/ratelimit"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+	"github.com/example/spire-workload-demo/internal/scenarios"
+	"github.com/example/spire-workload-demo/internal/topology"
 )
 
 // Pattern identifiers for SPIFFE integration patterns (FR-019)
 const (
 	PatternEnvoySDS     = "envoy-sds"
 	PatternSpiffeHelper = "spiffe-helper"
+	// Local SQLite file: no connection, no authentication
+	PatternLocalSQLite = "local-unauthenticated"
 )
 
 // Event types for structured logging
@@ -18,12 +26,28 @@ const (
 	EventConnectionSuccess = "connection_success"
 	EventConnectionFailure = "connection_failure"
 	EventCertRotation      = "cert_rotation"
+	EventLeaderAcquired    = "leader_acquired"
+	EventLeaderLost        = "leader_lost"
+	EventOrderTransition   = "order_transition"
+	EventPoolWaitSpike     = "pool_wait_spike"
+	EventReplicaEjected    = "replica_ejected"
+	EventReplicaRestored   = "replica_restored"
+	EventDeadlineExceeded  = "deadline_exceeded"
+	EventCircuitState      = "circuit_state_change"
+	EventQueryTrace        = "query_trace"
+	EventSlowQuery         = "slow_query"
+	EventOpenAPIViolation  = "openapi_violation"
+	EventScenarioResult    = scenarios.EventResult
+	EventRateLimited       = ratelimit.EventLimited
+	EventRateLimitReload   = ratelimit.EventReload
 )
 
 // Logger wraps slog with structured fields for pattern-aware logging
 type Logger struct {
 	logger    *slog.Logger
 	component string
+	// Connections observed through the logged connection events
+	topology *topology.Recorder
 }
 
 // NewLogger creates a new structured logger for the backend component
@@ -45,12 +69,20 @@ func NewLogger(component string) *Logger {
 		})
 	}
 
+	// Every ctx-aware call picks up the request's correlation ID
+	recorder := topology.NewRecorder(topology.Observer(component))
 	return &Logger{
-		logger:    slog.New(handler),
+		logger:    slog.New(reqctx.NewLogHandler(topology.NewLogHandler(handler, recorder))),
 		component: component,
+		topology:  recorder,
 	}
 }
 
+// Topology returns the connections logged so far as a graph
+func (l *Logger) Topology() *api.Topology {
+	return l.topology.Graph()
+}
+
 // LogEvent logs a structured event with pattern identifier and SPIFFE context
 func (l *Logger) LogEvent(ctx context.Context, pattern, event, spiffeID, peerSPIFFEID, message string) {
 	l.logger.InfoContext(ctx,
@@ -106,7 +138,27 @@ func (l *Logger) Info(message string, args ...any) {
 	l.logger.Info(message, append([]any{"component", l.component}, args...)...)
 }
 
+// Warn logs a warning message
+func (l *Logger) Warn(message string, args ...any) {
+	l.logger.Warn(message, append([]any{"component", l.component}, args...)...)
+}
+
 // Error logs an error message
 func (l *Logger) Error(message string, args ...any) {
 	l.logger.Error(message, append([]any{"component", l.component}, args...)...)
 }
+
+// InfoContext logs an informational message with the correlation ID in ctx
+func (l *Logger) InfoContext(ctx context.Context, message string, args ...any) {
+	l.logger.InfoContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
+
+// WarnContext logs a warning message with the correlation ID in ctx
+func (l *Logger) WarnContext(ctx context.Context, message string, args ...any) {
+	l.logger.WarnContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
+
+// ErrorContext logs an error message with the correlation ID in ctx
+func (l *Logger) ErrorContext(ctx context.Context, message string, args ...any) {
+	l.logger.ErrorContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrationLockKey is the advisory lock key that serializes schema migrations
+// across backend replicas
+const migrationLockKey = 7_261_001
+
+// migration is a single versioned schema change. sqlite holds the
+// equivalent statements for the local SQLite driver.
+type migration struct {
+	version int
+	name    string
+	sql     string
+	sqlite  string
+}
+
+// migrations lists schema changes in the order they are applied.
+// Version 1 mirrors the postgres init.sql so a fresh database converges
+// to the same schema regardless of which side created it.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_orders",
+		sql: `CREATE TABLE IF NOT EXISTS orders (
+			id SERIAL PRIMARY KEY,
+			description VARCHAR(255) NOT NULL,
+			status VARCHAR(50) NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+		CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at DESC);`,
+		// PostgreSQL is seeded by init.sql; the local file seeds itself
+		sqlite: `CREATE TABLE IF NOT EXISTS orders (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			description VARCHAR(255) NOT NULL,
+			status VARCHAR(50) NOT NULL DEFAULT 'pending',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+		CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at DESC);
+		INSERT INTO orders (description, status) VALUES
+			('Order for laptop and accessories', 'completed'),
+			('Bulk office supplies order', 'pending'),
+			('Emergency replacement keyboard', 'shipped'),
+			('Software license renewal', 'completed'),
+			('Conference room equipment', 'processing');`,
+	},
+	{
+		version: 2,
+		name:    "orders_updated_at",
+		sql: `ALTER TABLE orders ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+		CREATE INDEX IF NOT EXISTS idx_orders_status_updated_at ON orders(status, updated_at);`,
+		// SQLite cannot add a column with a non-constant default
+		sqlite: `ALTER TABLE orders ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+		UPDATE orders SET updated_at = created_at;
+		CREATE INDEX IF NOT EXISTS idx_orders_status_updated_at ON orders(status, updated_at);`,
+	},
+	{
+		// Orders are owned by the SPIFFE ID that created them. The connecting
+		// user is a superuser and bypasses RLS, so request-scoped transactions
+		// switch to the caller's mapped role (demo_app by default) before
+		// querying.
+		version: 3,
+		name:    "orders_row_level_security",
+		sql: `ALTER TABLE orders ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+		UPDATE orders SET created_by = 'spiffe://example.org/ns/demo/sa/frontend' WHERE created_by IS NULL;
+		ALTER TABLE orders ALTER COLUMN created_by SET DEFAULT NULLIF(current_setting('app.spiffe_id', true), '');
+		ALTER TABLE orders ALTER COLUMN created_by SET NOT NULL;
+
+		DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'demo_app') THEN
+				CREATE ROLE demo_app NOLOGIN;
+			END IF;
+		END
+		$$;
+		GRANT SELECT, INSERT, UPDATE ON TABLE orders TO demo_app;
+		GRANT USAGE, SELECT ON SEQUENCE orders_id_seq TO demo_app;
+
+		ALTER TABLE orders ENABLE ROW LEVEL SECURITY;
+		DROP POLICY IF EXISTS orders_owner ON orders;
+		CREATE POLICY orders_owner ON orders
+			USING (
+				created_by = current_setting('app.spiffe_id', true)
+				OR (
+					current_setting('app.rls_scope', true) = 'namespace'
+					AND regexp_replace(created_by, '/sa/[^/]*$', '') =
+						regexp_replace(current_setting('app.spiffe_id', true), '/sa/[^/]*$', '')
+				)
+			)
+			WITH CHECK (created_by = current_setting('app.spiffe_id', true));`,
+		// No roles or RLS in SQLite; keep the column for schema parity
+		sqlite: `ALTER TABLE orders ADD COLUMN created_by VARCHAR(255) NOT NULL DEFAULT 'spiffe://example.org/ns/demo/sa/frontend';`,
+	},
+	{
+		// Read-only role for least-privilege identities in DB_ROLE_MAP
+		version: 4,
+		name:    "demo_readonly_role",
+		sql: `DO $$
+		BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'demo_readonly') THEN
+				CREATE ROLE demo_readonly NOLOGIN;
+			END IF;
+		END
+		$$;
+		GRANT SELECT ON TABLE orders TO demo_readonly;`,
+		sqlite: `SELECT 1;`,
+	},
+}
+
+// Migrate applies pending schema migrations. A session-level advisory lock
+// ensures only one replica migrates at a time.
+func (db *DB) Migrate(ctx context.Context) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	// A local SQLite file has a single writer and no advisory locks
+	if !db.isSQLite() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+		}
+		statements := m.sql
+		if db.isSQLite() {
+			statements = m.sqlite
+		}
+		if _, err := db.exec(ctx, tx, statements); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		if _, err := db.exec(ctx, tx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+		}
+
+		db.logger.Info("Applied schema migration", "version", m.version, "name", m.name)
+	}
+
+	return nil
+}
@@ -1,34 +1,23 @@
 package backend
 
-import "time"
+import "github.com/example/spire-workload-demo/api"
 
-// Order represents a demo order entity in the database
-type Order struct {
-	ID          int       `json:"id" db:"id"`
-	Description string    `json:"description" db:"description"`
-	Status      string    `json:"status" db:"status"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-}
+// Wire types are defined once in the api package, shared with the frontend
+// and the typed client
+type (
+	Order              = api.Order
+	CreateOrderRequest = api.CreateOrderRequest
+	UpdateOrderRequest = api.UpdateOrderRequest
+	ConnectionStatus   = api.ConnectionStatus
+	DemoResult         = api.DemoResult
+	HealthResponse     = api.HealthResponse
+	PoolStats          = api.PoolStats
+)
 
 // Order status constants
 const (
-	StatusPending    = "pending"
-	StatusProcessing = "processing"
-	StatusCompleted  = "completed"
-	StatusFailed     = "failed"
+	StatusPending    = api.StatusPending
+	StatusProcessing = api.StatusProcessing
+	StatusCompleted  = api.StatusCompleted
+	StatusFailed     = api.StatusFailed
 )
-
-// ConnectionStatus represents the status of a connection attempt
-type ConnectionStatus struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Pattern string `json:"pattern"` // "envoy-sds" or "spiffe-helper"
-}
-
-// DemoResult represents the result of the full demo flow
-type DemoResult struct {
-	FrontendToBackend ConnectionStatus `json:"frontend_to_backend"`
-	BackendToDatabase ConnectionStatus `json:"backend_to_database"`
-	Orders            []Order          `json:"orders,omitempty"`
-	Timestamp         time.Time        `json:"timestamp"`
-}
@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"errors"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/api/openapi"
+)
+
+// OpenAPITypes returns the backend's wire types keyed by the component schema
+// they are sent as
+func OpenAPITypes() map[string]any {
+	return map[string]any{
+		"Order":               Order{},
+		"CreateOrderRequest":  CreateOrderRequest{},
+		"UpdateOrderRequest":  UpdateOrderRequest{},
+		"ConnectionStatus":    ConnectionStatus{},
+		"DemoResult":          DemoResult{},
+		"HealthResponse":      HealthResponse{},
+		"RoleMappingResponse": RoleMappingResponse{},
+		"RoleMappingEntry":    RoleMappingEntry{},
+		"PoolStats":           PoolStats{},
+		"Problem":             api.Problem{},
+		"ScenarioReport":      api.ScenarioReport{},
+		"Topology":            api.Topology{},
+		"TopologyNode":        api.TopologyNode{},
+		"TopologyEdge":        api.TopologyEdge{},
+	}
+}
+
+// CheckOpenAPI verifies that the backend's wire types match their schemas
+// in the OpenAPI document
+func CheckOpenAPI(doc *openapi.Document) error {
+	var errs []error
+	for name, v := range OpenAPITypes() {
+		errs = append(errs, doc.CheckType(name, v))
+	}
+	return errors.Join(errs...)
+}
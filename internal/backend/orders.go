@@ -0,0 +1,287 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// maxOrderBodyBytes bounds create and update request bodies
+const maxOrderBodyBytes = 64 << 10
+
+// maxDescriptionLength matches the orders.description column
+const maxDescriptionLength = 255
+
+// anonymousCaller is recorded as created_by when no caller identity was
+// forwarded, e.g. when the backend is called without Envoy in local mode
+const anonymousCaller = "anonymous"
+
+var (
+	// ErrOrderNotFound means the order does not exist or is not visible to
+	// the caller under row-level security
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrInvalidOrder means a create or update request failed validation
+	ErrInvalidOrder = errors.New("invalid order")
+)
+
+const orderColumns = `id, description, status, created_at`
+
+// GetOrder retrieves a single order visible to the caller
+func (db *DB) GetOrder(ctx context.Context, id int) (*Order, error) {
+	ctx, cancel := db.withQueryTimeout(ctx, OpGetOrder)
+	defer cancel()
+
+	var order *Order
+	err := db.requestTx(ctx, true, func(tx *sql.Tx) error {
+		var err error
+		order, err = db.queryOrder(ctx, tx,
+			`SELECT `+orderColumns+` FROM orders WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		return nil, timeoutCause(ctx, err)
+	}
+	return order, nil
+}
+
+// CreateOrder inserts a pending order owned by the caller
+func (db *DB) CreateOrder(ctx context.Context, req CreateOrderRequest) (*Order, error) {
+	if err := validateDescription(req.Description); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := db.withQueryTimeout(ctx, OpCreateOrder)
+	defer cancel()
+
+	createdBy := CallerSPIFFEID(ctx)
+	if createdBy == "" {
+		createdBy = anonymousCaller
+	}
+
+	var order *Order
+	err := db.requestTx(ctx, false, func(tx *sql.Tx) error {
+		var err error
+		order, err = db.queryOrder(ctx, tx,
+			`INSERT INTO orders (description, status, created_by) VALUES ($1, $2, $3)
+			RETURNING `+orderColumns,
+			req.Description, StatusPending, createdBy)
+		return err
+	})
+	if err != nil {
+		return nil, timeoutCause(ctx, err)
+	}
+
+	db.logger.InfoContext(ctx, "Created order",
+		"order_id", order.ID,
+		"pattern", db.Pattern(),
+		"caller_spiffe_id", CallerSPIFFEID(ctx),
+	)
+	return order, nil
+}
+
+// UpdateOrder changes the description and/or status of an order visible to
+// the caller
+func (db *DB) UpdateOrder(ctx context.Context, id int, req UpdateOrderRequest) (*Order, error) {
+	if req.Description == nil && req.Status == nil {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidOrder)
+	}
+	if req.Description != nil {
+		if err := validateDescription(*req.Description); err != nil {
+			return nil, err
+		}
+	}
+	if req.Status != nil && !validStatus(*req.Status) {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidOrder, *req.Status)
+	}
+
+	ctx, cancel := db.withQueryTimeout(ctx, OpUpdateOrder)
+	defer cancel()
+
+	var order *Order
+	err := db.requestTx(ctx, false, func(tx *sql.Tx) error {
+		var err error
+		order, err = db.queryOrder(ctx, tx,
+			`UPDATE orders SET description = COALESCE($1, description), status = COALESCE($2, status),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3
+			RETURNING `+orderColumns,
+			req.Description, req.Status, id)
+		return err
+	})
+	if err != nil {
+		return nil, timeoutCause(ctx, err)
+	}
+
+	db.logger.InfoContext(ctx, "Updated order",
+		"order_id", order.ID,
+		"status", order.Status,
+		"pattern", db.Pattern(),
+		"caller_spiffe_id", CallerSPIFFEID(ctx),
+	)
+	return order, nil
+}
+
+// queryOrder runs a statement returning at most one order row
+func (db *DB) queryOrder(ctx context.Context, tx *sql.Tx, query string, args ...any) (*Order, error) {
+	var order *Order
+	err := db.queryRows(ctx, tx, query, args, func(rows *sql.Rows) error {
+		order = &Order{}
+		if err := rows.Scan(&order.ID, &order.Description, &order.Status, &order.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+func validateDescription(description string) error {
+	if strings.TrimSpace(description) == "" {
+		return fmt.Errorf("%w: description is required", ErrInvalidOrder)
+	}
+	if len(description) > maxDescriptionLength {
+		return fmt.Errorf("%w: description exceeds %d bytes", ErrInvalidOrder, maxDescriptionLength)
+	}
+	return nil
+}
+
+func validStatus(status string) bool {
+	switch status {
+	case StatusPending, StatusProcessing, StatusCompleted, StatusFailed:
+		return true
+	}
+	return false
+}
+
+// CreateOrderHandler handles POST /api/orders requests
+func (h *Handler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
+	var req CreateOrderRequest
+	if !decodeOrderRequest(w, r, &req) {
+		return
+	}
+
+	order, err := db.CreateOrder(r.Context(), req)
+	if h.writeOrderError(w, r, err, "create order") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", fmt.Sprintf("/api/orders/%d", order.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// OrderHandler handles GET /api/orders/{id} requests
+func (h *Handler) OrderHandler(w http.ResponseWriter, r *http.Request) {
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := db.GetOrder(r.Context(), id)
+	if h.writeOrderError(w, r, err, "retrieve order") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// UpdateOrderHandler handles PUT /api/orders/{id} requests
+func (h *Handler) UpdateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
+	id, ok := orderID(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateOrderRequest
+	if !decodeOrderRequest(w, r, &req) {
+		return
+	}
+
+	order, err := db.UpdateOrder(r.Context(), id, req)
+	if h.writeOrderError(w, r, err, "update order") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// writeOrderError maps an order operation error to a response. It returns
+// false, writing nothing, when err is nil.
+func (h *Handler) writeOrderError(w http.ResponseWriter, r *http.Request, err error, action string) bool {
+	if err == nil {
+		return false
+	}
+	if h.writeTimeout(w, r, err) {
+		return true
+	}
+
+	var problem *api.Problem
+	switch {
+	case errors.Is(err, ErrInvalidOrder):
+		problem = api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrOrderNotFound):
+		problem = api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "Order not found")
+	case errors.Is(err, ErrNoRoleMapping):
+		problem = api.NewProblem(api.ProblemForbidden, http.StatusForbidden, "Caller is not mapped to a database role")
+	case isPermissionDenied(err):
+		problem = api.NewProblem(api.ProblemForbidden, http.StatusForbidden, "Caller is not permitted to "+action)
+	case errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrBulkheadFull):
+		problem = api.NewProblem(api.ProblemDatabaseUnavailable, http.StatusServiceUnavailable,
+			"Database temporarily unavailable: "+err.Error()).WithHop(HopBackendToDatabase)
+	default:
+		h.logger.ErrorContext(r.Context(), "Failed to "+action, "error", err)
+		problem = api.NewProblem(api.ProblemInternal, http.StatusInternalServerError,
+			"Failed to "+action).WithHop(HopBackendToDatabase)
+	}
+	api.WriteProblem(w, r, problem)
+	return true
+}
+
+func orderID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid order id"))
+		return 0, false
+	}
+	return id, true
+}
+
+func decodeOrderRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxOrderBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid request body: "+err.Error()))
+		return false
+	}
+	return true
+}
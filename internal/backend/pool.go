@@ -0,0 +1,158 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type poolWaitKey struct{}
+
+// withPoolWait returns a context in which request transactions add the time
+// they waited for a pooled connection to *wait
+func withPoolWait(ctx context.Context, wait *time.Duration) context.Context {
+	return context.WithValue(ctx, poolWaitKey{}, wait)
+}
+
+// addPoolWait records a wait in the accumulator set by withPoolWait, if any
+func addPoolWait(ctx context.Context, d time.Duration) {
+	if wait, ok := ctx.Value(poolWaitKey{}).(*time.Duration); ok {
+		*wait += d
+	}
+}
+
+// PoolMonitor periodically samples connection pool statistics and warns
+// when callers spend too long waiting for a connection
+type PoolMonitor struct {
+	db     *sql.DB
+	config *DBConfig
+	logger *Logger
+
+	mu   sync.RWMutex
+	last PoolStats
+	prev sql.DBStats
+}
+
+func newPoolMonitor(db *sql.DB, config *DBConfig, logger *Logger) *PoolMonitor {
+	m := &PoolMonitor{db: db, config: config, logger: logger}
+	m.sample()
+	return m
+}
+
+// Run samples pool statistics every PoolSampleInterval until ctx is cancelled
+func (m *PoolMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.PoolSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sample()
+		}
+	}
+}
+
+// Stats returns the most recent sample
+func (m *PoolMonitor) Stats() PoolStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.last
+}
+
+// Current reads the pool statistics now, without waiting for the next
+// sample. Cumulative counters can then be compared over short intervals,
+// e.g. a load test.
+func (m *PoolMonitor) Current() PoolStats {
+	return m.poolStats(m.db.Stats())
+}
+
+func (m *PoolMonitor) poolStats(stats sql.DBStats) PoolStats {
+	return PoolStats{
+		SampledAt:         time.Now(),
+		MaxOpenConns:      stats.MaxOpenConnections,
+		MaxIdleConns:      m.config.MaxIdleConns,
+		ConnMaxLifetime:   m.config.ConnMaxLifetime.String(),
+		OpenConnections:   stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDurationMS:    float64(stats.WaitDuration) / float64(time.Millisecond),
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
+
+func (m *PoolMonitor) sample() {
+	stats := m.db.Stats()
+
+	m.mu.Lock()
+	prev := m.prev
+	m.prev = stats
+	m.last = m.poolStats(stats)
+	m.mu.Unlock()
+
+	// Warn on the wait time accumulated since the previous sample
+	waited := stats.WaitDuration - prev.WaitDuration
+	if threshold := m.config.PoolWaitWarnThreshold; threshold > 0 && waited > threshold {
+		m.logger.Warn("Connection pool wait duration spike",
+			"event", EventPoolWaitSpike,
+			"wait_duration", waited,
+			"wait_count", stats.WaitCount-prev.WaitCount,
+			"in_use", stats.InUse,
+			"max_open_conns", stats.MaxOpenConnections,
+		)
+	}
+}
+
+// PoolHandler handles GET /admin/db/pool requests with the pool's current
+// statistics
+func (h *Handler) PoolHandler(w http.ResponseWriter, r *http.Request) {
+	db := h.database(w, r)
+	if db == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(db.pool.Current())
+}
+
+// MetricsHandler handles GET /metrics requests in the Prometheus text format
+func (h *Handler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	h.rateLimits.WriteMetrics(w)
+
+	db := h.db.Load()
+	if db == nil {
+		writeMetric(w, "backend_db_up", "gauge", "Whether the startup database connection has succeeded.", 0)
+		return
+	}
+	writeMetric(w, "backend_db_up", "gauge", "Whether the startup database connection has succeeded.", 1)
+
+	stats := db.pool.Stats()
+	writeMetric(w, "backend_db_pool_max_open_connections", "gauge", "Configured maximum open connections.", float64(stats.MaxOpenConns))
+	writeMetric(w, "backend_db_pool_open_connections", "gauge", "Established connections, in use and idle.", float64(stats.OpenConnections))
+	writeMetric(w, "backend_db_pool_in_use_connections", "gauge", "Connections currently in use.", float64(stats.InUse))
+	writeMetric(w, "backend_db_pool_idle_connections", "gauge", "Idle connections.", float64(stats.Idle))
+	writeMetric(w, "backend_db_pool_wait_count_total", "counter", "Connections waited for.", float64(stats.WaitCount))
+	writeMetric(w, "backend_db_pool_wait_duration_seconds_total", "counter", "Time blocked waiting for a connection.", stats.WaitDurationMS/1000)
+	writeMetric(w, "backend_db_pool_max_idle_closed_total", "counter", "Connections closed due to SetMaxIdleConns.", float64(stats.MaxIdleClosed))
+	writeMetric(w, "backend_db_pool_max_idle_time_closed_total", "counter", "Connections closed due to SetConnMaxIdleTime.", float64(stats.MaxIdleTimeClosed))
+	writeMetric(w, "backend_db_pool_max_lifetime_closed_total", "counter", "Connections closed due to SetConnMaxLifetime.", float64(stats.MaxLifetimeClosed))
+	writeMetric(w, "backend_db_circuit_state", "gauge", "Database circuit breaker state (0 closed, 1 half-open, 2 open).", circuitStateValue(db.CircuitState()))
+
+	fmt.Fprintf(w, "# HELP backend_db_bulkhead_in_flight In-flight database operations per endpoint.\n# TYPE backend_db_bulkhead_in_flight gauge\n")
+	for endpoint, count := range db.bulkhead.inFlight() {
+		fmt.Fprintf(w, "backend_db_bulkhead_in_flight{endpoint=%q} %d\n", endpoint, count)
+	}
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,203 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// QueryLogConfig controls query timing and slow query logging
+type QueryLogConfig struct {
+	// Queries slower than this are logged with their fingerprint
+	SlowThreshold time.Duration
+	// Fraction (0.0-1.0) of slow SELECTs re-run with EXPLAIN (ANALYZE)
+	ExplainSampleRate float64
+	// Log every query, not only slow ones
+	TraceAll bool
+}
+
+// NewQueryLogConfigFromEnv creates query logging settings from environment variables
+func NewQueryLogConfigFromEnv() QueryLogConfig {
+	return QueryLogConfig{
+		SlowThreshold:     getEnvAsDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+		ExplainSampleRate: getEnvAsFloat("DB_SLOW_QUERY_EXPLAIN_RATE", 0),
+		TraceAll:          getEnvAsBool("DB_TRACE_QUERIES", false),
+	}
+}
+
+// exec runs a statement on q and traces it
+func (db *DB) exec(ctx context.Context, q queryer, query string, args ...any) (sql.Result, error) {
+	start := time.Now()
+	result, err := q.ExecContext(ctx, query, args...)
+
+	var rows int64
+	if err == nil {
+		rows, _ = result.RowsAffected()
+	}
+	db.traceQuery(ctx, q, query, args, time.Since(start), rows, err)
+	return result, err
+}
+
+// queryRows runs a query on q, calls scan for each row and traces the query
+// including the time spent reading rows
+func (db *DB) queryRows(ctx context.Context, q queryer, query string, args []any, scan func(*sql.Rows) error) error {
+	start := time.Now()
+	var count int64
+
+	err := func() error {
+		rows, err := q.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			if err := scan(rows); err != nil {
+				return err
+			}
+			count++
+		}
+		return rows.Err()
+	}()
+
+	db.traceQuery(ctx, q, query, args, time.Since(start), count, err)
+	return err
+}
+
+// traceQuery logs slow queries (and every query when TraceAll is set).
+// Argument values are never logged, only their types.
+func (db *DB) traceQuery(ctx context.Context, q queryer, query string, args []any, elapsed time.Duration, rows int64, err error) {
+	cfg := db.config.QueryLog
+	slow := cfg.SlowThreshold > 0 && elapsed >= cfg.SlowThreshold
+	if !slow && !cfg.TraceAll {
+		return
+	}
+
+	event := EventQueryTrace
+	if slow {
+		event = EventSlowQuery
+	}
+
+	statement := normalizeQuery(query)
+	fields := []any{
+		"event", event,
+		"fingerprint", queryFingerprint(statement),
+		"statement", statement,
+		"args", redactArgs(args),
+		"duration_ms", float64(elapsed) / float64(time.Millisecond),
+		"rows", rows,
+	}
+	if err != nil {
+		fields = append(fields, "error", err.Error())
+	}
+
+	if !slow {
+		db.logger.InfoContext(ctx, "Query executed", fields...)
+		return
+	}
+
+	if err == nil && cfg.ExplainSampleRate > 0 && rand.Float64() < cfg.ExplainSampleRate {
+		fields = append(fields, "plan", db.explain(ctx, q, query, args))
+	}
+	db.logger.WarnContext(ctx, "Slow query", fields...)
+}
+
+// explain re-runs a SELECT with EXPLAIN (ANALYZE) on the same queryer, so
+// role and row-level security settings of the transaction still apply.
+// Inside a transaction it runs under a savepoint: a failed EXPLAIN would
+// otherwise abort the caller's transaction and fail the request.
+func (db *DB) explain(ctx context.Context, q queryer, query string, args []any) string {
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT") {
+		return "skipped: not a SELECT"
+	}
+	if db.isSQLite() {
+		return "skipped: SQLite has no EXPLAIN ANALYZE"
+	}
+
+	_, inTx := q.(*sql.Tx)
+	if inTx {
+		if _, err := q.ExecContext(ctx, "SAVEPOINT explain_plan"); err != nil {
+			return "explain failed: " + err.Error()
+		}
+	}
+
+	var plan []string
+	err := func() error {
+		rows, err := q.QueryContext(ctx, "EXPLAIN (ANALYZE, BUFFERS) "+query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var line string
+			if err := rows.Scan(&line); err != nil {
+				return err
+			}
+			plan = append(plan, line)
+		}
+		return rows.Err()
+	}()
+
+	if inTx {
+		release := "RELEASE SAVEPOINT explain_plan"
+		if err != nil {
+			release = "ROLLBACK TO SAVEPOINT explain_plan"
+		}
+		// Still release when ctx ran out during EXPLAIN, so the caller's
+		// transaction stays usable
+		if _, releaseErr := q.ExecContext(context.WithoutCancel(ctx), release); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}
+	if err != nil {
+		return "explain failed: " + err.Error()
+	}
+	return strings.Join(plan, "\n")
+}
+
+var (
+	whitespacePattern = regexp.MustCompile(`\s+`)
+	// Bind placeholders ($1) are matched first so their digits are not
+	// taken for literals; normalizeQuery keeps them as they are
+	literalPattern = regexp.MustCompile(`\$\d+|'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b`)
+)
+
+// normalizeQuery collapses whitespace and replaces inline literals with ?
+// so the same statement always produces the same fingerprint
+func normalizeQuery(query string) string {
+	query = whitespacePattern.ReplaceAllString(strings.TrimSpace(query), " ")
+	return literalPattern.ReplaceAllStringFunc(query, func(match string) string {
+		if strings.HasPrefix(match, "$") {
+			return match
+		}
+		return "?"
+	})
+}
+
+// queryFingerprint returns a short stable hash of a normalized statement
+func queryFingerprint(statement string) string {
+	h := fnv.New64a()
+	h.Write([]byte(statement))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// redactArgs replaces argument values with their types
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = fmt.Sprintf("%T", arg)
+	}
+	return redacted
+}
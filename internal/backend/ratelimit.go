@@ -0,0 +1,48 @@
+package backend
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/example/spire-workload-demo/internal/ratelimit"
+)
+
+// defaultRateLimits apply when RATE_LIMIT_FILE is unset. /api/demo reads
+// every order; the limit stays above LOADTEST_MAX_RATE so a frontend load
+// test is not throttled by its own backend.
+var defaultRateLimits = ratelimit.Config{
+	Routes: map[string]ratelimit.Limit{
+		"/api/demo": {Rate: 250, Burst: 250},
+	},
+}
+
+// newRateLimits limits each caller per route, keyed by the SPIFFE ID
+// IdentityMiddleware extracted, or the remote IP for requests that did not
+// come through Envoy
+func newRateLimits(logger *Logger) *ratelimit.Service {
+	return ratelimit.NewService("backend", "caller", rateLimitKey, defaultRateLimits, logger)
+}
+
+func rateLimitKey(r *http.Request) string {
+	if id := CallerSPIFFEID(r.Context()); id != "" {
+		return id
+	}
+	return ratelimit.RemoteIP(r)
+}
+
+// StartRateLimiter loads the per-route limits from RATE_LIMIT_FILE, or the
+// defaults when it is unset, and reloads the file every
+// RATE_LIMIT_RELOAD_INTERVAL until ctx is cancelled. Call it before serving.
+func (h *Handler) StartRateLimiter(ctx context.Context) error {
+	return h.rateLimits.Start(ctx,
+		getEnv("RATE_LIMIT_FILE", ""),
+		getEnvAsDuration("RATE_LIMIT_RELOAD_INTERVAL", 10*time.Second),
+	)
+}
+
+// RateLimitMiddleware limits each caller per route. Routes are the mux
+// patterns, as in the limits file.
+func (h *Handler) RateLimitMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return h.rateLimits.Middleware(mux, next)
+}
@@ -0,0 +1,204 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaConfig describes a read replica. Each replica has its own
+// spiffe-helper certificate paths; they default to the primary's.
+type ReplicaConfig struct {
+	Name      string
+	Host      string
+	Port      string
+	SSLCert   string
+	SSLKey    string
+	SSLRootCA string
+}
+
+// ReplicaRouting controls how read-only queries are routed to replicas
+type ReplicaRouting struct {
+	Replicas       []ReplicaConfig
+	HealthInterval time.Duration
+	// Replicas whose replay lag exceeds MaxLag are ejected until they catch up
+	MaxLag time.Duration
+}
+
+// NewReplicaRoutingFromEnv reads DB_REPLICA_<n>_* variables (n = 1, 2, ...)
+// until DB_REPLICA_<n>_HOST is unset
+func NewReplicaRoutingFromEnv(primary *DBConfig) ReplicaRouting {
+	routing := ReplicaRouting{
+		HealthInterval: getEnvAsDuration("DB_REPLICA_HEALTH_INTERVAL", 5*time.Second),
+		MaxLag:         getEnvAsDuration("DB_REPLICA_MAX_LAG", 5*time.Second),
+	}
+
+	for n := 1; ; n++ {
+		prefix := "DB_REPLICA_" + strconv.Itoa(n) + "_"
+		host := getEnv(prefix+"HOST", "")
+		if host == "" {
+			break
+		}
+		routing.Replicas = append(routing.Replicas, ReplicaConfig{
+			Name:      getEnv(prefix+"NAME", "replica-"+strconv.Itoa(n)),
+			Host:      host,
+			Port:      getEnv(prefix+"PORT", primary.Port),
+			SSLCert:   getEnv(prefix+"SSL_CERT", primary.SSLCert),
+			SSLKey:    getEnv(prefix+"SSL_KEY", primary.SSLKey),
+			SSLRootCA: getEnv(prefix+"SSL_ROOT_CA", primary.SSLRootCA),
+		})
+	}
+
+	return routing
+}
+
+// replica is a read replica connection pool with its current health
+type replica struct {
+	config  ReplicaConfig
+	db      *sql.DB
+	healthy atomic.Bool
+	lag     atomic.Int64 // nanoseconds
+}
+
+// replicaSet round-robins read-only queries across healthy replicas
+type replicaSet struct {
+	replicas []*replica
+	routing  ReplicaRouting
+	logger   *Logger
+	next     atomic.Uint64
+}
+
+// newReplicaSet opens a pool per replica. Replicas start ejected and join
+// rotation after their first successful health check.
+func newReplicaSet(config *DBConfig, logger *Logger) (*replicaSet, error) {
+	set := &replicaSet{routing: config.ReplicaRouting, logger: logger}
+	for _, rc := range config.ReplicaRouting.Replicas {
+		db, err := openPool(config, rc.Host, rc.Port, rc.SSLCert, rc.SSLKey, rc.SSLRootCA)
+		if err != nil {
+			set.Close()
+			return nil, fmt.Errorf("failed to open replica %s: %w", rc.Name, err)
+		}
+		set.replicas = append(set.replicas, &replica{config: rc, db: db})
+	}
+	return set, nil
+}
+
+// pick returns a healthy replica, or nil if none is available
+func (s *replicaSet) pick() *replica {
+	n := len(s.replicas)
+	start := int(s.next.Add(1) % uint64(max(n, 1)))
+	for i := range n {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Run health-checks every replica each HealthInterval until ctx is cancelled
+func (s *replicaSet) Run(ctx context.Context) {
+	if len(s.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.routing.HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, r := range s.replicas {
+			s.check(ctx, r)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// replicaLagQuery measures how far a replica's replay is behind, in
+// seconds. The time since the last replayed transaction keeps growing while
+// the primary is idle, so it only counts when the replica has received WAL
+// it has not replayed yet. A server not in recovery has no lag.
+const replicaLagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() THEN 0
+	WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// check pings a replica and measures replay lag, ejecting or restoring it
+func (s *replicaSet) check(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, s.routing.HealthInterval)
+	defer cancel()
+
+	var lagSeconds float64
+	err := r.db.QueryRowContext(ctx, replicaLagQuery).Scan(&lagSeconds)
+
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	r.lag.Store(int64(lag))
+
+	if err == nil && s.routing.MaxLag > 0 && lag > s.routing.MaxLag {
+		err = fmt.Errorf("replication lag %s exceeds %s", lag, s.routing.MaxLag)
+	}
+
+	healthy := err == nil
+	if r.healthy.Swap(healthy) == healthy {
+		return
+	}
+
+	if healthy {
+		s.logger.Info("Read replica restored to rotation",
+			"event", EventReplicaRestored,
+			"replica", r.config.Name,
+			"target", r.config.Host,
+			"lag", lag,
+		)
+	} else {
+		s.logger.Warn("Read replica ejected from rotation",
+			"event", EventReplicaEjected,
+			"replica", r.config.Name,
+			"target", r.config.Host,
+			"error", err.Error(),
+		)
+	}
+}
+
+// Close closes every replica pool
+func (s *replicaSet) Close() error {
+	var firstErr error
+	for _, r := range s.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+type readYourWritesKey struct{}
+
+// WithReadYourWrites returns a context whose reads are pinned to the primary
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, true)
+}
+
+// ReadYourWrites reports whether reads in ctx must go to the primary
+func ReadYourWrites(ctx context.Context) bool {
+	pinned, _ := ctx.Value(readYourWritesKey{}).(bool)
+	return pinned
+}
+
+// ReadPreferenceMiddleware pins a request's reads to the primary when it
+// carries ?read_your_writes=true
+func (h *Handler) ReadPreferenceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pinned, _ := strconv.ParseBool(r.URL.Query().Get("read_your_writes")); pinned {
+			r = r.WithContext(WithReadYourWrites(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,200 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standby stands in for a Postgres replica: it answers the lag query with
+// a configurable value, or fails as if the server were down
+type standby struct {
+	mu   sync.Mutex
+	lag  float64
+	down bool
+}
+
+func (s *standby) set(lag float64, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lag, s.down = lag, down
+}
+
+var (
+	standbysMu sync.Mutex
+	standbys   = map[string]*standby{}
+)
+
+func init() {
+	sql.Register("standby", standbyDriver{})
+}
+
+type standbyDriver struct{}
+
+func (standbyDriver) Open(name string) (driver.Conn, error) {
+	standbysMu.Lock()
+	defer standbysMu.Unlock()
+	s, ok := standbys[name]
+	if !ok {
+		return nil, errors.New("unknown standby " + name)
+	}
+	return standbyConn{s}, nil
+}
+
+type standbyConn struct{ s *standby }
+
+func (c standbyConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c standbyConn) Close() error              { return nil }
+func (c standbyConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c standbyConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if query != replicaLagQuery {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	c.s.mu.Lock()
+	defer c.s.mu.Unlock()
+	if c.s.down {
+		return nil, driver.ErrBadConn
+	}
+	return &lagRows{lag: c.s.lag}, nil
+}
+
+type lagRows struct {
+	lag  float64
+	done bool
+}
+
+func (r *lagRows) Columns() []string { return []string{"lag"} }
+func (r *lagRows) Close() error      { return nil }
+func (r *lagRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.lag
+	return nil
+}
+
+// newTestReplicas returns a DB whose reads can route to two stand-in
+// replicas, with MaxLag of 5s
+func newTestReplicas(t *testing.T) (*DB, map[string]*standby) {
+	t.Helper()
+
+	set := &replicaSet{
+		routing: ReplicaRouting{HealthInterval: time.Second, MaxLag: 5 * time.Second},
+		logger:  NewLogger("backend-test"),
+	}
+	stubs := map[string]*standby{}
+	for _, name := range []string{"replica-1", "replica-2"} {
+		dsn := t.Name() + "/" + name
+		stubs[name] = &standby{}
+		standbysMu.Lock()
+		standbys[dsn] = stubs[name]
+		standbysMu.Unlock()
+
+		db, err := sql.Open("standby", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		set.replicas = append(set.replicas, &replica{config: ReplicaConfig{Name: name, Host: name}, db: db})
+	}
+	t.Cleanup(func() { set.Close() })
+
+	return &DB{logger: set.logger, replicas: set}, stubs
+}
+
+// checkAll runs one health-check round, as replicaSet.Run does per tick
+func checkAll(db *DB) {
+	for _, r := range db.replicas.replicas {
+		db.replicas.check(context.Background(), r)
+	}
+}
+
+// readers returns how often each target served n reads from ctx
+func readers(db *DB, ctx context.Context, n int) map[string]int {
+	seen := map[string]int{}
+	for range n {
+		_, name := db.reader(ctx)
+		seen[name]++
+	}
+	return seen
+}
+
+func TestReplicasJoinRotationAfterHealthCheck(t *testing.T) {
+	db, _ := newTestReplicas(t)
+
+	if seen := readers(db, context.Background(), 4); seen["primary"] != 4 {
+		t.Fatalf("reads before the first health check = %v, want all on primary", seen)
+	}
+
+	checkAll(db)
+	seen := readers(db, context.Background(), 10)
+	if seen["replica-1"] != 5 || seen["replica-2"] != 5 {
+		t.Fatalf("reads = %v, want an even round-robin across both replicas", seen)
+	}
+}
+
+func TestLaggingReplicaEjectedUntilCaughtUp(t *testing.T) {
+	db, stubs := newTestReplicas(t)
+	checkAll(db)
+
+	stubs["replica-1"].set(30, false)
+	checkAll(db)
+	if seen := readers(db, context.Background(), 6); seen["replica-2"] != 6 {
+		t.Fatalf("reads with replica-1 lagging = %v, want all on replica-2", seen)
+	}
+
+	stubs["replica-1"].set(1, false)
+	checkAll(db)
+	if seen := readers(db, context.Background(), 6); seen["replica-1"] != 3 || seen["replica-2"] != 3 {
+		t.Fatalf("reads after replica-1 caught up = %v, want both replicas", seen)
+	}
+}
+
+func TestUnreachableReplicasFallBackToPrimary(t *testing.T) {
+	db, stubs := newTestReplicas(t)
+	checkAll(db)
+
+	stubs["replica-1"].set(0, true)
+	stubs["replica-2"].set(0, true)
+	checkAll(db)
+	if seen := readers(db, context.Background(), 4); seen["primary"] != 4 {
+		t.Fatalf("reads with every replica down = %v, want all on primary", seen)
+	}
+}
+
+func TestReadYourWritesPinsToPrimary(t *testing.T) {
+	db, _ := newTestReplicas(t)
+	checkAll(db)
+
+	for _, tc := range []struct {
+		query  string
+		pinned bool
+	}{
+		{"", false},
+		{"?read_your_writes=true", true},
+		{"?read_your_writes=1", true},
+		{"?read_your_writes=false", false},
+		{"?read_your_writes=maybe", false},
+	} {
+		var seen map[string]int
+		h := &Handler{}
+		handler := h.ReadPreferenceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = readers(db, r.Context(), 4)
+		}))
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/orders"+tc.query, nil))
+
+		if pinned := seen["primary"] == 4; pinned != tc.pinned {
+			t.Errorf("%q: reads = %v, pinned to primary = %v, want %v", tc.query, seen, pinned, tc.pinned)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+var (
+	// ErrCircuitOpen is returned without touching PostgreSQL while the breaker is open
+	ErrCircuitOpen = errors.New("database circuit breaker is open")
+	// ErrBulkheadFull is returned when an endpoint already has its maximum
+	// in-flight database work
+	ErrBulkheadFull = errors.New("database bulkhead full")
+)
+
+// ResilienceConfig holds circuit breaker and bulkhead settings
+type ResilienceConfig struct {
+	// Consecutive failures that open the breaker
+	FailureThreshold int
+	// How long the breaker stays open before letting probes through
+	OpenTimeout time.Duration
+	// Concurrent probe requests allowed while half-open
+	HalfOpenMaxRequests int
+	// Maximum in-flight database operations per endpoint
+	BulkheadLimit int
+	// How long a request waits for a bulkhead slot before being rejected
+	BulkheadMaxWait time.Duration
+}
+
+// NewResilienceConfigFromEnv creates circuit breaker and bulkhead settings from environment variables
+func NewResilienceConfigFromEnv() ResilienceConfig {
+	return ResilienceConfig{
+		FailureThreshold:    getEnvAsInt("DB_BREAKER_FAILURE_THRESHOLD", 5),
+		OpenTimeout:         getEnvAsDuration("DB_BREAKER_OPEN_TIMEOUT", 15*time.Second),
+		HalfOpenMaxRequests: getEnvAsInt("DB_BREAKER_HALF_OPEN_MAX_REQUESTS", 1),
+		BulkheadLimit:       getEnvAsInt("DB_BULKHEAD_LIMIT", 5),
+		BulkheadMaxWait:     getEnvAsDuration("DB_BULKHEAD_MAX_WAIT", 100*time.Millisecond),
+	}
+}
+
+// circuitBreaker stops sending queries to PostgreSQL after repeated failures
+// so requests fail fast instead of piling up as pool waiters
+type circuitBreaker struct {
+	config ResilienceConfig
+	logger *Logger
+
+	mu               sync.Mutex
+	state            string
+	failures         int
+	openedAt         time.Time
+	halfOpenInFlight int
+}
+
+func newCircuitBreaker(config ResilienceConfig, logger *Logger) *circuitBreaker {
+	return &circuitBreaker{config: config, logger: logger, state: CircuitClosed}
+}
+
+// State returns the current breaker state
+func (b *circuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Execute runs fn unless the breaker is open, recording its outcome
+func (b *circuitBreaker) Execute(fn func() error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.record(probe, err)
+	return err
+}
+
+// allow decides whether a call may proceed and whether it is a half-open probe
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == CircuitOpen && time.Since(b.openedAt) >= b.config.OpenTimeout {
+		b.transition(CircuitHalfOpen)
+	}
+
+	switch b.state {
+	case CircuitOpen:
+		return false, fmt.Errorf("%w (retry in %s)", ErrCircuitOpen,
+			(b.config.OpenTimeout - time.Since(b.openedAt)).Round(time.Second))
+	case CircuitHalfOpen:
+		if b.halfOpenInFlight >= b.config.HalfOpenMaxRequests {
+			return false, fmt.Errorf("%w (half-open probe in progress)", ErrCircuitOpen)
+		}
+		b.halfOpenInFlight++
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+func (b *circuitBreaker) record(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if probe {
+		b.halfOpenInFlight--
+	}
+
+	switch classifyOutcome(err) {
+	case outcomeNeutral:
+		// The probe slot is free again; the breaker waits for a call that
+		// reaches PostgreSQL
+		return
+	case outcomeSuccess:
+		b.failures = 0
+		if probe && b.state == CircuitHalfOpen {
+			b.transition(CircuitClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if (probe && b.state == CircuitHalfOpen) || (b.state == CircuitClosed && b.failures >= b.config.FailureThreshold) {
+		b.openedAt = time.Now()
+		b.transition(CircuitOpen)
+	}
+}
+
+// transition changes state and logs the event; callers hold b.mu
+func (b *circuitBreaker) transition(to string) {
+	from := b.state
+	b.state = to
+	if to == CircuitClosed {
+		b.failures = 0
+	}
+
+	b.logger.Warn("Database circuit breaker state changed",
+		"event", EventCircuitState,
+		"pattern", PatternSpiffeHelper,
+		"from", from,
+		"to", to,
+		"failures", b.failures,
+	)
+}
+
+// outcome is what a call's result says about PostgreSQL's health
+type outcome int
+
+const (
+	// PostgreSQL answered, possibly with a caller-specific error
+	outcomeSuccess outcome = iota
+	// The call ended before PostgreSQL answered for reasons of the caller's
+	// own, so it says nothing either way
+	outcomeNeutral
+	outcomeFailure
+)
+
+// classifyOutcome sorts err into an outcome. Only a call PostgreSQL answered
+// (no error, missing order, denied role or row) may close a half-open
+// breaker; invalid input, an unmapped caller, a caller that went away or ran
+// out of budget and a full bulkhead neither trip nor close it.
+func classifyOutcome(err error) outcome {
+	switch {
+	case err == nil,
+		errors.Is(err, ErrOrderNotFound),
+		isAccessRuleViolation(err):
+		return outcomeSuccess
+	case errors.Is(err, ErrInvalidOrder),
+		errors.Is(err, ErrNoRoleMapping),
+		errors.Is(err, ErrBudgetExhausted),
+		errors.Is(err, ErrBulkheadFull),
+		errors.Is(err, context.Canceled):
+		return outcomeNeutral
+	default:
+		return outcomeFailure
+	}
+}
+
+// isAccessRuleViolation reports whether PostgreSQL rejected the statement
+// itself: SQLSTATE class 42, which includes insufficient privilege and row
+// rejected by a row-level security WITH CHECK policy (both 42501)
+func isAccessRuleViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code.Class() == "42"
+}
+
+// isPermissionDenied reports whether PostgreSQL denied the caller's role or
+// a row-level security policy rejected the row
+func isPermissionDenied(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "42501"
+}
+
+// bulkhead caps concurrent database work per endpoint so one busy route
+// cannot take every pooled connection
+type bulkhead struct {
+	config ResilienceConfig
+
+	mu    sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newBulkhead(config ResilienceConfig) *bulkhead {
+	return &bulkhead{config: config, slots: make(map[string]chan struct{})}
+}
+
+// acquire takes a slot for endpoint, waiting up to BulkheadMaxWait
+func (b *bulkhead) acquire(ctx context.Context, endpoint string) (func(), error) {
+	if b.config.BulkheadLimit <= 0 {
+		return func() {}, nil
+	}
+
+	b.mu.Lock()
+	slots, ok := b.slots[endpoint]
+	if !ok {
+		slots = make(chan struct{}, b.config.BulkheadLimit)
+		b.slots[endpoint] = slots
+	}
+	b.mu.Unlock()
+
+	timer := time.NewTimer(b.config.BulkheadMaxWait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: %s has %d operations in flight", ErrBulkheadFull, endpoint, b.config.BulkheadLimit)
+	case <-ctx.Done():
+		return nil, context.Cause(ctx)
+	}
+}
+
+// inFlight returns the current in-flight count per endpoint
+func (b *bulkhead) inFlight() map[string]int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	counts := make(map[string]int, len(b.slots))
+	for endpoint, slots := range b.slots {
+		counts[endpoint] = len(slots)
+	}
+	return counts
+}
+
+type endpointKey struct{}
+
+// EndpointMiddleware tags the request context with the route pattern mux
+// matches, e.g. "GET /api/orders/{id}", so the database bulkhead can account
+// work per endpoint without a slot pool per order id
+func (h *Handler) EndpointMiddleware(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Path
+		if _, pattern := mux.Handler(r); pattern != "" {
+			endpoint = pattern
+		}
+		ctx := context.WithValue(r.Context(), endpointKey{}, endpoint)
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func endpointFromContext(ctx context.Context) string {
+	if endpoint, ok := ctx.Value(endpointKey{}).(string); ok {
+		return endpoint
+	}
+	return "internal"
+}
+
+// guard runs request-scoped database work inside the endpoint's bulkhead
+// and the circuit breaker. Errors are wrapped with the context's cause
+// before the breaker sees them, so it can tell the caller's exhausted
+// budget from a query timeout.
+func (db *DB) guard(ctx context.Context, fn func() error) error {
+	release, err := db.bulkhead.acquire(ctx, endpointFromContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return db.breaker.Execute(func() error {
+		return timeoutCause(ctx, fn())
+	})
+}
+
+// CircuitState returns the database circuit breaker state
+func (db *DB) CircuitState() string {
+	return db.breaker.State()
+}
+
+// circuitStateValue maps a breaker state to a metric value
+func circuitStateValue(state string) float64 {
+	switch state {
+	case CircuitHalfOpen:
+		return 1
+	case CircuitOpen:
+		return 2
+	default:
+		return 0
+	}
+}
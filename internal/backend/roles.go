@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ErrNoRoleMapping is returned when a caller has no database role mapping
+// and unmapped callers are denied
+var ErrNoRoleMapping = errors.New("no database role mapped for caller SPIFFE ID")
+
+// RoleMapping maps caller SPIFFE IDs to the Postgres role their
+// request-scoped transactions run as (SET LOCAL ROLE)
+type RoleMapping struct {
+	Enabled bool
+	Roles   map[string]string
+	// Role for callers without a mapping; empty denies them
+	Fallback string
+}
+
+// NewRoleMappingFromEnv parses DB_ROLE_MAP ("spiffe-id=role,...") and
+// DB_ROLE_FALLBACK into a role mapping
+func NewRoleMappingFromEnv() (*RoleMapping, error) {
+	mapping := &RoleMapping{
+		Enabled:  getEnvAsBool("DB_ROLE_MAPPING_ENABLED", true),
+		Roles:    make(map[string]string),
+		Fallback: getEnv("DB_ROLE_FALLBACK", ""),
+	}
+
+	raw := getEnv("DB_ROLE_MAP", "spiffe://example.org/ns/demo/sa/frontend=demo_app")
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		spiffeID, role, ok := strings.Cut(entry, "=")
+		spiffeID, role = strings.TrimSpace(spiffeID), strings.TrimSpace(role)
+		if !ok || !strings.HasPrefix(spiffeID, "spiffe://") || role == "" {
+			return nil, fmt.Errorf("invalid DB_ROLE_MAP entry %q: want spiffe://...=role", entry)
+		}
+		mapping.Roles[spiffeID] = role
+	}
+
+	return mapping, nil
+}
+
+// Resolve returns the role for the caller, the fallback role if the caller is
+// unmapped, or ErrNoRoleMapping if there is no fallback
+func (m *RoleMapping) Resolve(callerSPIFFEID string) (string, error) {
+	if role, ok := m.Roles[callerSPIFFEID]; ok {
+		return role, nil
+	}
+	if m.Fallback != "" {
+		return m.Fallback, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrNoRoleMapping, callerSPIFFEID)
+}
+
+// setRoleStatement builds a SET LOCAL ROLE statement with a quoted identifier
+func setRoleStatement(role string) string {
+	return "SET LOCAL ROLE " + pq.QuoteIdentifier(role)
+}
+
+// RoleMappingEntry is a single row of the role mapping table
+type RoleMappingEntry struct {
+	SPIFFEID string `json:"spiffe_id"`
+	Role     string `json:"role"`
+}
+
+// RoleMappingResponse is returned by GET /admin/db/roles
+type RoleMappingResponse struct {
+	Enabled  bool               `json:"enabled"`
+	Mappings []RoleMappingEntry `json:"mappings"`
+	// Fallback role for unmapped callers; empty means they are denied
+	Fallback string `json:"fallback,omitempty"`
+	Unmapped string `json:"unmapped"` // "fallback" or "deny"
+}
+
+// RolesHandler handles GET /admin/db/roles requests
+func (h *Handler) RolesHandler(w http.ResponseWriter, r *http.Request) {
+	mapping := h.config.RoleMapping
+
+	response := RoleMappingResponse{
+		Enabled:  mapping.Enabled,
+		Mappings: make([]RoleMappingEntry, 0, len(mapping.Roles)),
+		Fallback: mapping.Fallback,
+		Unmapped: "deny",
+	}
+	if mapping.Fallback != "" {
+		response.Unmapped = "fallback"
+	}
+	for spiffeID, role := range mapping.Roles {
+		response.Mappings = append(response.Mappings, RoleMappingEntry{SPIFFEID: spiffeID, Role: role})
+	}
+	sort.Slice(response.Mappings, func(i, j int) bool {
+		return response.Mappings[i].SPIFFEID < response.Mappings[j].SPIFFEID
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
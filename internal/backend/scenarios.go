@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/scenarios"
+)
+
+// scenarioDemo runs a negative-path scenario on the backend-to-database hop
+// (Pattern 2). A secondary pool presents a client certificate from
+// ScenarioCertDir instead of the spiffe-helper SVID; PostgreSQL should
+// refuse it. The frontend-to-backend hop is the normal one.
+func (h *Handler) scenarioDemo(w http.ResponseWriter, r *http.Request, name string) {
+	ctx := r.Context()
+
+	if name != api.ScenarioUnauthorizedDBClient {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest,
+			fmt.Sprintf("Unknown scenario %q", name)))
+		return
+	}
+	if h.config.Driver == DriverSQLite {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemScenarioUnavailable, http.StatusServiceUnavailable,
+			"SQLite mode has no database connection to present a client certificate on").WithHop(HopBackendToDatabase))
+		return
+	}
+
+	certFile := filepath.Join(h.config.ScenarioCertDir, name+".pem")
+	keyFile := filepath.Join(h.config.ScenarioCertDir, name+"-key.pem")
+	presented, err := certSPIFFEID(certFile)
+	if err != nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemScenarioUnavailable, http.StatusServiceUnavailable,
+			"Scenario certificate not available: "+err.Error()).WithHop(HopBackendToDatabase))
+		return
+	}
+
+	// Same server, user and trust bundle as the primary pool; only the
+	// client certificate differs
+	db, err := openPool(h.config, h.config.Host, h.config.Port, certFile, keyFile, h.config.SSLRootCA)
+	if err != nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInternal, http.StatusInternalServerError, err.Error()))
+		return
+	}
+	defer db.Close()
+
+	h.logger.LogConnectionAttempt(ctx, PatternSpiffeHelper, h.config.Host, presented)
+	start := time.Now()
+	err = timeoutCause(ctx, db.PingContext(ctx))
+	latencyMs := float64(time.Since(start).Microseconds()) / 1000
+	if h.writeTimeout(w, r, err) {
+		return
+	}
+	if err != nil {
+		h.logger.LogConnectionFailure(ctx, PatternSpiffeHelper, h.config.Host, presented, err)
+	} else {
+		h.logger.LogConnectionSuccess(ctx, PatternSpiffeHelper, h.config.Host, presented, h.config.ServerSPIFFEID)
+	}
+
+	report := &api.ScenarioReport{
+		Name:        name,
+		Description: "Backend connects to PostgreSQL with a client certificate whose SPIFFE ID has no database user mapping",
+		Hop:         HopBackendToDatabase,
+		SPIFFEID:    presented,
+		Expected:    "PostgreSQL refuses the connection",
+	}
+	status := ConnectionStatus{
+		Pattern:   PatternSpiffeHelper,
+		LatencyMs: latencyMs,
+	}
+
+	var pqErr *pq.Error
+	switch {
+	case err == nil:
+		status.Success = true
+		status.Message = "PostgreSQL accepted the unauthorized client certificate"
+		report.Observed = "Connection accepted"
+	case errors.As(err, &pqErr) && pqErr.Code.Class() == "28":
+		// Class 28: invalid authorization specification
+		status.Message = "PostgreSQL rejected the client certificate: " + pqErr.Message
+		report.Observed = "Rejected by PostgreSQL authentication: " + pqErr.Message
+		report.Passed = true
+	case scenarios.IsRemoteTLSRejection(err):
+		status.Message = "PostgreSQL rejected the TLS handshake: " + err.Error()
+		report.Observed = "TLS handshake rejected by PostgreSQL: " + err.Error()
+		report.Passed = true
+	default:
+		status.Message = "Failed to connect to PostgreSQL: " + err.Error()
+		report.Observed = "Connection failed before PostgreSQL could decide: " + err.Error()
+	}
+
+	scenarios.Log(ctx, h.logger, report)
+
+	result := DemoResult{
+		FrontendToBackend: ConnectionStatus{
+			Success: true,
+			Message: "Envoy validated frontend SPIFFE ID via SDS",
+			Pattern: PatternEnvoySDS,
+		},
+		BackendToDatabase: status,
+		Timestamp:         start,
+		Scenario:          report,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// certSPIFFEID returns the SPIFFE ID in the first certificate of a PEM
+// file, or an error if the certificate has expired
+func certSPIFFEID(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("%s contains no PEM certificate", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if err := scenarios.CheckNotExpired(cert); err != nil {
+		return "", err
+	}
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String(), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no SPIFFE ID", path)
+}
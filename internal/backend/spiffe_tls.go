@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"time"
+)
+
+// SSL modes for DBConfig.SSLMode. All but SSLModeVerifySPIFFE are handled
+// by lib/pq itself.
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+	// SSLModeVerifySPIFFE validates the server chain against SSLRootCA and
+	// requires the server certificate's URI SAN to equal ServerSPIFFEID.
+	// SVIDs carry SPIFFE IDs rather than DNS names, so verify-full's hostname
+	// check does not apply to them.
+	SSLModeVerifySPIFFE = "verify-spiffe"
+)
+
+// validateSSLMode rejects unknown modes, and modes that do not verify the
+// server unless allowInsecure is set
+func validateSSLMode(mode, serverSPIFFEID string, allowInsecure bool) error {
+	switch mode {
+	case SSLModeVerifyCA, SSLModeVerifyFull:
+		return nil
+	case SSLModeVerifySPIFFE:
+		if serverSPIFFEID == "" {
+			return errors.New("sslmode verify-spiffe requires DB_SERVER_SPIFFE_ID")
+		}
+		return nil
+	case SSLModeDisable, SSLModeRequire:
+		if !allowInsecure {
+			return fmt.Errorf("sslmode %q does not verify the server; set DB_ALLOW_INSECURE_SSLMODE=true to use it anyway", mode)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported sslmode %q", mode)
+	}
+}
+
+// postgresSSLRequest is the SSLRequest message: length 8, code 80877103
+var postgresSSLRequest = []byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}
+
+// spiffeDialer negotiates TLS itself so it can verify the server's SPIFFE ID.
+// lib/pq runs with sslmode=disable on top of the returned TLS connection.
+// Certificates are read on every dial, so rotated SVIDs written by
+// spiffe-helper are picked up by new pool connections.
+type spiffeDialer struct {
+	sslCert        string
+	sslKey         string
+	sslRootCA      string
+	serverSPIFFEID string
+	dialer         net.Dialer
+}
+
+// Dial implements pq.Dialer
+func (d *spiffeDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialTimeout implements pq.Dialer
+func (d *spiffeDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.DialContext(ctx, network, address)
+}
+
+// DialContext implements pq.DialerContext
+func (d *spiffeDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	tlsConfig, err := d.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if err := requestSSL(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake with %s failed: %w", address, err)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// requestSSL asks the server to switch to TLS before the startup message
+func requestSSL(conn net.Conn) error {
+	if _, err := conn.Write(postgresSSLRequest); err != nil {
+		return fmt.Errorf("failed to send SSLRequest: %w", err)
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("failed to read SSLRequest reply: %w", err)
+	}
+	if reply[0] != 'S' {
+		return errors.New("server does not support SSL")
+	}
+	return nil
+}
+
+// tlsConfig loads the client SVID and trust bundle and builds a config that
+// verifies the server by chain and SPIFFE ID instead of hostname
+func (d *spiffeDialer) tlsConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(d.sslCert, d.sslKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	bundle, err := os.ReadFile(d.sslRootCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trust bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("trust bundle %s contains no certificates", d.sslRootCA)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		// Hostname verification is replaced by VerifyPeerCertificate below
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifySPIFFEPeer(rawCerts, roots, d.serverSPIFFEID)
+		},
+	}, nil
+}
+
+// verifySPIFFEPeer validates the presented chain against roots and checks
+// that the leaf certificate carries the expected SPIFFE ID
+func verifySPIFFEPeer(rawCerts [][]byte, roots *x509.CertPool, expectedID string) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := certs[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return fmt.Errorf("server certificate chain not trusted: %w", err)
+	}
+
+	var presented []string
+	for _, uri := range leaf.URIs {
+		presented = append(presented, uri.String())
+	}
+	if !slices.Contains(presented, expectedID) {
+		return fmt.Errorf("server SPIFFE ID mismatch: want %s, got %v", expectedID, presented)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite" // SQLite driver (pure Go, builds with CGO_ENABLED=0)
+)
+
+// Storage drivers for DBConfig.Driver
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite stores orders in a local file for offline demos without
+	// Kubernetes or PostgreSQL. There is no network hop and no
+	// authentication, so no SPIFFE pattern applies.
+	DriverSQLite = "sqlite"
+)
+
+// newSQLiteDB opens the local SQLite file. Certificates, retries, replicas,
+// roles and row-level security do not apply to a local file.
+func newSQLiteDB(ctx context.Context, config *DBConfig, logger *Logger) (*DB, error) {
+	spiffeID := getEnv("SPIFFE_ID", "spiffe://example.org/ns/demo/sa/backend")
+	logger.LogConnectionAttempt(ctx, PatternLocalSQLite, config.SQLitePath, spiffeID)
+
+	db, err := sql.Open("sqlite", "file:"+config.SQLitePath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		logger.LogConnectionFailure(ctx, PatternLocalSQLite, config.SQLitePath, spiffeID, err)
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection avoids busy errors
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		logger.LogConnectionFailure(ctx, PatternLocalSQLite, config.SQLitePath, spiffeID, err)
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	logger.Info("Local SQLite storage opened",
+		"pattern", PatternLocalSQLite,
+		"path", config.SQLitePath,
+		"authenticated", false,
+	)
+
+	return &DB{
+		DB:       db,
+		logger:   logger,
+		config:   config,
+		pool:     newPoolMonitor(db, config, logger),
+		replicas: &replicaSet{logger: logger},
+		breaker:  newCircuitBreaker(config.Resilience, logger),
+		bulkhead: newBulkhead(config.Resilience),
+	}, nil
+}
+
+// isSQLite reports whether the local SQLite driver is in use
+func (db *DB) isSQLite() bool {
+	return db.config.Driver == DriverSQLite
+}
+
+// Pattern returns the integration pattern that describes the database hop
+func (db *DB) Pattern() string {
+	if db.isSQLite() {
+		return PatternLocalSQLite
+	}
+	return PatternSpiffeHelper
+}
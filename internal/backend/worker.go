@@ -0,0 +1,251 @@
+package backend
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/example/spire-workload-demo/internal/reqctx"
+)
+
+// workerLockKey is the advisory lock key held by the replica that drives
+// scheduled order processing
+const workerLockKey = 7_261_002
+
+// claimOrdersQuery locks eligible orders; SKIP LOCKED lets concurrent
+// claimers pass over rows another transaction already holds
+const claimOrdersQuery = `SELECT id FROM orders
+	WHERE status = $1 AND updated_at <= NOW() - make_interval(secs => $2)
+	ORDER BY updated_at
+	LIMIT $3
+	FOR UPDATE SKIP LOCKED`
+
+// claimOrdersQuerySQLite is claimOrdersQuery for the single-writer SQLite file
+const claimOrdersQuerySQLite = `SELECT id FROM orders
+	WHERE status = $1 AND updated_at <= datetime('now', $2)
+	ORDER BY updated_at
+	LIMIT $3`
+
+// WorkerConfig holds order-processing worker configuration
+type WorkerConfig struct {
+	Enabled bool
+	// How often the leader polls for work and other replicas retry the lock
+	Interval  time.Duration
+	BatchSize int
+	// Minimum time an order stays in a status before the worker advances it
+	PendingDelay    time.Duration
+	ProcessingDelay time.Duration
+	// Fraction (0.0-1.0) of processing orders that end up failed
+	FailureRate float64
+}
+
+// NewWorkerConfigFromEnv creates worker configuration from environment variables
+func NewWorkerConfigFromEnv() *WorkerConfig {
+	return &WorkerConfig{
+		Enabled:         getEnvAsBool("WORKER_ENABLED", false),
+		Interval:        getEnvAsDuration("WORKER_INTERVAL", 5*time.Second),
+		BatchSize:       getEnvAsInt("WORKER_BATCH_SIZE", 5),
+		PendingDelay:    getEnvAsDuration("WORKER_PENDING_DELAY", 10*time.Second),
+		ProcessingDelay: getEnvAsDuration("WORKER_PROCESSING_DELAY", 20*time.Second),
+		FailureRate:     getEnvAsFloat("WORKER_FAILURE_RATE", 0.1),
+	}
+}
+
+// Worker moves orders through the status machine. Every replica runs one,
+// but only the holder of the advisory lock does any work.
+type Worker struct {
+	db       *DB
+	logger   *Logger
+	config   *WorkerConfig
+	spiffeID string
+}
+
+// NewWorker creates a new order-processing worker
+func NewWorker(db *DB, config *WorkerConfig, logger *Logger) *Worker {
+	return &Worker{
+		db:       db,
+		logger:   logger,
+		config:   config,
+		spiffeID: getEnv("SPIFFE_ID", "spiffe://example.org/ns/demo/sa/backend"),
+	}
+}
+
+// Run competes for leadership and processes orders until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	w.logger.Info("Order worker started",
+		"interval", w.config.Interval,
+		"batch_size", w.config.BatchSize,
+		"pending_delay", w.config.PendingDelay,
+		"processing_delay", w.config.ProcessingDelay,
+		"failure_rate", w.config.FailureRate,
+		"spiffe_id", w.spiffeID,
+	)
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	// A local SQLite file has a single process and no advisory locks
+	if w.db.isSQLite() {
+		w.process(ctx, ticker, nil)
+		w.logger.Info("Order worker stopped")
+		return
+	}
+
+	// The advisory lock is held on a pool of its own, so the leader does not
+	// take one of the request pool's connections for as long as it leads
+	config := w.db.config
+	lockDB, err := openPool(config, config.Host, config.Port, config.SSLCert, config.SSLKey, config.SSLRootCA)
+	if err != nil {
+		w.logger.Error("Order worker could not open its lock connection", "error", err, "spiffe_id", w.spiffeID)
+		return
+	}
+	defer lockDB.Close()
+	lockDB.SetMaxOpenConns(1)
+	lockDB.SetMaxIdleConns(1)
+
+	for {
+		if err := w.lead(ctx, ticker, lockDB); err != nil && ctx.Err() == nil {
+			w.logger.Error("Order worker leadership lost", "error", err, "event", EventLeaderLost, "spiffe_id", w.spiffeID)
+		}
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Order worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead tries to take the advisory lock and, if successful, processes orders
+// on every tick while the lock connection stays healthy. It returns nil when
+// the lock is held by another replica.
+func (w *Worker) lead(ctx context.Context, ticker *time.Ticker, lockDB *sql.DB) error {
+	// Session-level advisory locks belong to a single connection, so pin one
+	conn, err := lockDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire lock connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, workerLockKey).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+	if !acquired {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, workerLockKey)
+
+	w.logger.Info("Order worker acquired leadership",
+		"event", EventLeaderAcquired,
+		"pattern", PatternSpiffeHelper,
+		"spiffe_id", w.spiffeID,
+	)
+
+	// Losing the connection releases the lock on the server side
+	return w.process(ctx, ticker, conn.PingContext)
+}
+
+// process handles a batch on every tick until ctx is cancelled or, when
+// holdsLock is set, until it reports the lock is gone
+func (w *Worker) process(ctx context.Context, ticker *time.Ticker, holdsLock func(context.Context) error) error {
+	for {
+		if err := w.processBatch(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("Order processing failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		if holdsLock != nil {
+			if err := holdsLock(ctx); err != nil {
+				return fmt.Errorf("lock connection lost: %w", err)
+			}
+		}
+	}
+}
+
+// processBatch advances eligible pending and processing orders. Each batch
+// gets its own correlation ID so its query logs can be grouped.
+func (w *Worker) processBatch(ctx context.Context) error {
+	ctx = reqctx.WithCorrelationID(ctx, reqctx.NewCorrelationID())
+
+	if err := w.advance(ctx, StatusPending, w.config.PendingDelay, func() string {
+		return StatusProcessing
+	}); err != nil {
+		return err
+	}
+
+	return w.advance(ctx, StatusProcessing, w.config.ProcessingDelay, func() string {
+		if rand.Float64() < w.config.FailureRate {
+			return StatusFailed
+		}
+		return StatusCompleted
+	})
+}
+
+// advance claims up to BatchSize orders that have been in status for at least
+// delay and moves each one to the status chosen by next
+func (w *Worker) advance(ctx context.Context, status string, delay time.Duration, next func() string) error {
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, age := claimOrdersQuery, any(delay.Seconds())
+	if w.db.isSQLite() {
+		query, age = claimOrdersQuerySQLite, fmt.Sprintf("-%f seconds", delay.Seconds())
+	}
+
+	var ids []int
+	err = w.db.queryRows(ctx, tx, query,
+		[]any{status, age, w.config.BatchSize},
+		func(rows *sql.Rows) error {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan order id: %w", err)
+			}
+			ids = append(ids, id)
+			return nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to claim %s orders: %w", status, err)
+	}
+
+	for _, id := range ids {
+		to := next()
+		if err := w.updateStatus(ctx, tx, id, to); err != nil {
+			return err
+		}
+		w.logger.InfoContext(ctx, "Order status changed",
+			"event", EventOrderTransition,
+			"order_id", id,
+			"from", status,
+			"to", to,
+			"spiffe_id", w.spiffeID,
+		)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit order transitions: %w", err)
+	}
+	return nil
+}
+
+func (w *Worker) updateStatus(ctx context.Context, tx *sql.Tx, id int, status string) error {
+	if _, err := w.db.exec(ctx, tx,
+		`UPDATE orders SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`,
+		status, id,
+	); err != nil {
+		return fmt.Errorf("failed to update order %d: %w", id, err)
+	}
+	return nil
+}
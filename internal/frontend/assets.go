@@ -0,0 +1,155 @@
+package frontend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+//go:embed static
+var embeddedStatic embed.FS
+
+// Cache-Control values for static assets. Fingerprinted URLs change whenever
+// the content does, so browsers may keep them forever; plain URLs must be
+// revalidated against the ETag.
+const (
+	cacheControlImmutable  = "public, max-age=31536000, immutable"
+	cacheControlRevalidate = "no-cache"
+)
+
+// staticAsset is one file under static/ with precomputed response metadata
+type staticAsset struct {
+	name        string
+	contentType string
+	etag        string
+	// name with a content hash before the extension, e.g. app.1a2b3c4d.js
+	fingerprinted string
+	body          []byte
+}
+
+func newStaticAsset(name string, body []byte) *staticAsset {
+	sum := sha256.Sum256(body)
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+
+	ext := path.Ext(name)
+	return &staticAsset{
+		name:          name,
+		contentType:   contentType,
+		etag:          `"` + hex.EncodeToString(sum[:16]) + `"`,
+		fingerprinted: strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext,
+		body:          body,
+	}
+}
+
+// staticAssets serves the UI assets embedded in the binary, or files from
+// a directory on disk when STATIC_PATH is set for development
+type staticAssets struct {
+	// Development override, read on every request so edits show up live
+	dir fs.FS
+
+	byName          map[string]*staticAsset
+	byFingerprinted map[string]*staticAsset
+}
+
+// newStaticAssets indexes the embedded assets. A non-empty dir must exist;
+// a mistyped STATIC_PATH fails startup instead of serving 404s.
+func newStaticAssets(dir string) (*staticAssets, error) {
+	if dir != "" {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return nil, fmt.Errorf("STATIC_PATH: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("STATIC_PATH: %s is not a directory", dir)
+		}
+		return &staticAssets{dir: os.DirFS(dir)}, nil
+	}
+
+	static, err := fs.Sub(embeddedStatic, "static")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &staticAssets{
+		byName:          make(map[string]*staticAsset),
+		byFingerprinted: make(map[string]*staticAsset),
+	}
+	err = fs.WalkDir(static, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := fs.ReadFile(static, name)
+		if err != nil {
+			return err
+		}
+		asset := newStaticAsset(name, body)
+		a.byName[asset.name] = asset
+		a.byFingerprinted[asset.fingerprinted] = asset
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to index embedded assets: %w", err)
+	}
+	return a, nil
+}
+
+// url returns the URL templates should reference for an asset: the
+// fingerprinted one when embedded, the plain one when live-editing
+func (a *staticAssets) url(name string) string {
+	if asset, ok := a.byName[name]; ok {
+		return "/static/" + asset.fingerprinted
+	}
+	return "/static/" + name
+}
+
+// ServeHTTP serves /static/<name> with its content type and a strong ETag
+func (a *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/static/")
+
+	asset, cacheControl := a.lookup(name)
+	if asset == nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "No static asset at "+r.URL.Path))
+		return
+	}
+
+	w.Header().Set("Content-Type", asset.contentType)
+	w.Header().Set("ETag", asset.etag)
+	w.Header().Set("Cache-Control", cacheControl)
+	http.ServeContent(w, r, asset.name, time.Time{}, bytes.NewReader(asset.body))
+}
+
+func (a *staticAssets) lookup(name string) (*staticAsset, string) {
+	if a.dir != nil {
+		if !fs.ValidPath(name) {
+			return nil, ""
+		}
+		body, err := fs.ReadFile(a.dir, name)
+		if err != nil {
+			return nil, ""
+		}
+		return newStaticAsset(name, body), cacheControlRevalidate
+	}
+
+	if asset, ok := a.byFingerprinted[name]; ok {
+		return asset, cacheControlImmutable
+	}
+	if asset, ok := a.byName[name]; ok {
+		return asset, cacheControlRevalidate
+	}
+	return nil, ""
+}
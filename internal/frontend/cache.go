@@ -0,0 +1,147 @@
+package frontend
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache lookup results, also returned to the browser in the X-Cache header
+const (
+	CacheHit   = "HIT"
+	CacheStale = "STALE"
+	CacheMiss  = "MISS"
+)
+
+type cacheEntry struct {
+	resp       *backendResponse
+	fetched    time.Time
+	refreshing bool
+}
+
+// responseCache is a small TTL cache with stale-while-revalidate. Entries
+// younger than ttl are served as hits. Entries younger than ttl+stale are
+// served immediately while a single background refresh replaces them. At
+// most maxEntries are kept; the oldest is evicted to make room.
+type responseCache struct {
+	ttl        time.Duration
+	stale      time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// gen changes on invalidate so fetches started before a write are dropped
+	gen uint64
+
+	hits, stales, misses atomic.Int64
+}
+
+func newResponseCache(ttl, stale time.Duration, maxEntries int) *responseCache {
+	return &responseCache{
+		ttl:        ttl,
+		stale:      stale,
+		maxEntries: max(maxEntries, 1),
+		entries:    make(map[string]*cacheEntry),
+	}
+}
+
+// get looks up key. refresh is true for exactly one caller per stale entry;
+// that caller must fetch a replacement and call set or abortRefresh.
+func (c *responseCache) get(key string) (resp *backendResponse, result string, refresh bool) {
+	resp, result, refresh = c.lookup(key)
+	switch result {
+	case CacheHit:
+		c.hits.Add(1)
+	case CacheStale:
+		c.stales.Add(1)
+	default:
+		c.misses.Add(1)
+	}
+	return resp, result, refresh
+}
+
+func (c *responseCache) lookup(key string) (*backendResponse, string, bool) {
+	if c.ttl <= 0 {
+		return nil, CacheMiss, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, CacheMiss, false
+	}
+
+	age := time.Since(entry.fetched)
+	switch {
+	case age < c.ttl:
+		return entry.resp, CacheHit, false
+	case age < c.ttl+c.stale:
+		refresh := !entry.refreshing
+		entry.refreshing = true
+		return entry.resp, CacheStale, refresh
+	default:
+		delete(c.entries, key)
+		return nil, CacheMiss, false
+	}
+}
+
+// generation returns the value to pass to set for a fetch starting now
+func (c *responseCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// set stores a fresh response for key unless the cache was invalidated
+// after the fetch began
+func (c *responseCache) set(key string, resp *backendResponse, gen uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		c.evictOldest()
+	}
+	c.entries[key] = &cacheEntry{resp: resp, fetched: time.Now()}
+}
+
+// evictOldest drops the entry fetched longest ago; callers hold c.mu
+func (c *responseCache) evictOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if oldestKey == "" || entry.fetched.Before(oldest) {
+			oldestKey, oldest = key, entry.fetched
+		}
+	}
+	delete(c.entries, oldestKey)
+}
+
+// abortRefresh lets a later request retry a failed background refresh
+func (c *responseCache) abortRefresh(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		entry.refreshing = false
+	}
+}
+
+// invalidate drops every entry, e.g. after a write through the frontend
+func (c *responseCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	clear(c.entries)
+}
+
+// stats returns lookup counts by result
+func (c *responseCache) stats() (hits, stales, misses int64) {
+	return c.hits.Load(), c.stales.Load(), c.misses.Load()
+}
@@ -0,0 +1,239 @@
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+)
+
+// BackendClient is the long-lived HTTP client for frontend-to-backend calls
+// through the local Envoy proxy. Connections to Envoy are pooled and reused.
+type BackendClient struct {
+	httpClient *http.Client
+	logger     *Logger
+
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	requests atomic.Int64
+	retries  atomic.Int64
+}
+
+// NewBackendClient creates the backend client from environment variables
+func NewBackendClient(logger *Logger) *BackendClient {
+	transport := &http.Transport{
+		Proxy: nil, // Envoy is local; never route through an HTTP proxy
+		DialContext: (&net.Dialer{
+			Timeout:   2 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: getEnvAsInt("BACKEND_MAX_IDLE_CONNS", 20),
+		MaxConnsPerHost:     getEnvAsInt("BACKEND_MAX_CONNS", 50),
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &BackendClient{
+		httpClient: &http.Client{
+			// Every backend call carries the correlation ID in its context
+			Transport: &reqctx.Transport{Base: transport},
+			// Safety net only; per-request deadlines come from the context
+			Timeout: getEnvAsDuration("BACKEND_CLIENT_TIMEOUT", 30*time.Second),
+		},
+		logger:         logger,
+		maxRetries:     getEnvAsInt("BACKEND_MAX_RETRIES", 2),
+		initialBackoff: getEnvAsDuration("BACKEND_RETRY_BACKOFF", 100*time.Millisecond),
+		maxBackoff:     getEnvAsDuration("BACKEND_RETRY_MAX_BACKOFF", time.Second),
+	}
+}
+
+// Do sends req, retrying idempotent requests on connection-level errors and
+// 503 responses (see retryableUnavailable). Each attempt carries the
+// remaining deadline budget.
+func (c *BackendClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+
+	for attempt := 0; ; attempt++ {
+		c.requests.Add(1)
+
+		attemptReq := req.Clone(ctx)
+		setRequestBudget(ctx, attemptReq)
+
+		resp, err := c.httpClient.Do(attemptReq)
+
+		reason := ""
+		var retryAfter time.Duration
+		switch {
+		case err != nil && ctx.Err() == nil:
+			reason = err.Error()
+		case err == nil && resp.StatusCode == http.StatusServiceUnavailable && retryable:
+			var ok bool
+			if retryAfter, ok = retryableUnavailable(resp); ok {
+				reason = resp.Status
+			}
+		}
+		if reason == "" || !retryable || attempt >= c.maxRetries {
+			return resp, err
+		}
+
+		delay := max(c.backoff(attempt+1), retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		c.retries.Add(1)
+		c.logger.InfoContext(ctx, "Retrying backend request",
+			"event", EventBackendRetry,
+			"pattern", PatternEnvoySDS,
+			"target", req.URL.String(),
+			"attempt", attempt+1,
+			"reason", reason,
+			"backoff", delay,
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		case <-time.After(delay):
+		}
+	}
+}
+
+// maxRetryProblemBytes bounds how much of a 503 body is read to decide
+// whether to retry it
+const maxRetryProblemBytes = 4 << 10
+
+// retryableUnavailable reports whether a 503 should be retried and the
+// delay its Retry-After header asks for, zero if it has none, e.g. Envoy's
+// own "no healthy upstream". The backend's database-unavailable problem is
+// not retried: its circuit breaker or bulkhead is shedding load, which a
+// retry would only add to. The body is left for the caller to read.
+func retryableUnavailable(resp *http.Response) (time.Duration, bool) {
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == api.ProblemContentType {
+		head, _ := io.ReadAll(io.LimitReader(resp.Body, maxRetryProblemBytes))
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+		var problem api.Problem
+		if json.Unmarshal(head, &problem) == nil && problem.Type == api.ProblemTypeURI(api.ProblemDatabaseUnavailable) {
+			return 0, false
+		}
+	}
+	return retryAfter, true
+}
+
+// backendResponse is a fully read backend response that can be replayed
+type backendResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// fetchBackend sends a request to the backend via Envoy (Pattern 1) within
+// the frontend's backend budget and reads the whole response
+func (h *Handler) fetchBackend(ctx context.Context, method, path string, body []byte) (*backendResponse, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, h.backendTimeout, errBackendBudgetExhausted)
+	defer cancel()
+
+	target := h.backendURL + path
+	h.logger.LogConnectionAttempt(ctx, PatternEnvoySDS, target, h.spiffeID)
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "frontend-demo-client")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, target, h.spiffeID, err)
+		if errors.Is(context.Cause(ctx), errBackendBudgetExhausted) {
+			return nil, fmt.Errorf("%w: %v", errBackendBudgetExhausted, err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backend response: %w", err)
+	}
+
+	peerSPIFFEID := "spiffe://example.org/ns/demo/sa/backend"
+	h.logger.LogConnectionSuccess(ctx, PatternEnvoySDS, target, h.spiffeID, peerSPIFFEID)
+	return &backendResponse{status: resp.StatusCode, header: resp.Header, body: data}, nil
+}
+
+// backoff returns the delay before the given retry (1-based) with jitter
+func (c *BackendClient) backoff(retry int) time.Duration {
+	d := c.initialBackoff << (retry - 1)
+	if d <= 0 || d > c.maxBackoff {
+		d = c.maxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Stats returns the number of backend requests sent and how many were retries
+func (c *BackendClient) Stats() (requests, retries int64) {
+	return c.requests.Load(), c.retries.Load()
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,135 @@
+package frontend
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// parseTemplates parses the page templates once at startup; a broken
+// template fails the process before it serves traffic. Templates link assets
+// with {{asset "name"}} so embedded builds get fingerprinted URLs.
+func parseTemplates(assets *staticAssets) *template.Template {
+	return template.Must(template.New("").
+		Funcs(template.FuncMap{"asset": assets.url}).
+		ParseFS(templateFS, "templates/*.html"))
+}
+
+// HopHealth is the live state of one hop in the demo path
+type HopHealth struct {
+	Hop     string
+	Pattern string
+	Path    string
+	Status  string
+	Detail  string
+	// Latency of the last health probe; zero when not measured
+	LatencyMs float64
+}
+
+// dashboardData is the context for templates/index.html
+type dashboardData struct {
+	SPIFFEID   string
+	BackendURL string
+	Hops       []HopHealth
+	Latest     *DemoRecord
+	Recent     []DemoRecord
+	Topology   *api.Topology
+}
+
+// IndexHandler renders the dashboard. It works without JavaScript; app.js
+// only enhances the demo form, draws the topology diagram and adds the
+// order browser.
+func (h *Handler) IndexHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "No page at "+r.URL.Path))
+			return
+		}
+
+		data := dashboardData{
+			SPIFFEID:   h.spiffeID,
+			BackendURL: h.backendURL,
+			Hops:       h.checkHops(),
+			Recent:     h.history.list(h.dashboardRuns),
+			Topology:   h.topology(r.Context()),
+		}
+		if len(data.Recent) > 0 {
+			data.Latest = &data.Recent[0]
+		}
+
+		// Render fully before writing so a template error can still be a 500
+		var buf bytes.Buffer
+		if err := h.templates.ExecuteTemplate(&buf, "index.html", data); err != nil {
+			h.logger.ErrorContext(r.Context(), "Failed to render dashboard", "error", err.Error())
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemInternal, http.StatusInternalServerError, "Failed to render dashboard"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		buf.WriteTo(w)
+	}
+}
+
+// DemoRunHandler handles POST /demo/run from the dashboard form when
+// JavaScript is unavailable, then redirects back to the dashboard
+func (h *Handler) DemoRunHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scenario := r.PostFormValue("scenario")
+		if scenario != "" && !knownScenario(scenario) {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest,
+				fmt.Sprintf("Unknown scenario %q", scenario)))
+			return
+		}
+
+		if _, err := h.runDemo(r.Context(), scenario); err != nil {
+			h.logger.ErrorContext(r.Context(), "Dashboard demo run failed", "error", err.Error())
+		}
+
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// checkHops maps the cached dependency health onto the demo's two hops
+func (h *Handler) checkHops() []HopHealth {
+	deps, _ := h.health.check()
+	backend := dependency(deps, DependencyBackend)
+	database := dependency(deps, DependencyDatabase)
+
+	return []HopHealth{
+		{
+			Hop:       HopFrontendToBackend,
+			Pattern:   PatternEnvoySDS,
+			Path:      "Frontend → Envoy → Backend",
+			Status:    backend.Status,
+			Detail:    hopDetail(backend, "Backend reachable over mTLS"),
+			LatencyMs: backend.LatencyMs,
+		},
+		{
+			Hop:       HopBackendToDatabase,
+			Pattern:   PatternSpiffeHelper,
+			Path:      "Backend → spiffe-helper → PostgreSQL",
+			Status:    database.Status,
+			Detail:    hopDetail(database, "Backend reports the database healthy"),
+			LatencyMs: database.LatencyMs,
+		},
+	}
+}
+
+func hopDetail(dep DependencyHealth, healthy string) string {
+	switch dep.Status {
+	case HealthHealthy:
+		return healthy
+	case HealthUnhealthy:
+		return dep.LastError
+	default:
+		return "Not determined (upstream hop unavailable)"
+	}
+}
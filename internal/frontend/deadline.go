@@ -0,0 +1,39 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// Deadline propagation headers shared with the backend
+const (
+	HeaderRequestBudget = api.HeaderRequestBudget
+	HeaderTimeoutHop    = api.HeaderTimeoutHop
+)
+
+// Hops reported when a deadline is exceeded
+const (
+	HopFrontendToBackend = api.HopFrontendToBackend
+	HopBackendToDatabase = api.HopBackendToDatabase
+)
+
+// errBackendBudgetExhausted is the context cause when the frontend's own
+// backend timeout fires
+var errBackendBudgetExhausted = errors.New("backend call budget exhausted")
+
+// setRequestBudget propagates the time left before ctx's deadline to the
+// backend so it can give up before the frontend stops waiting
+func setRequestBudget(ctx context.Context, req *http.Request) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	if remaining := time.Until(deadline).Milliseconds(); remaining > 0 {
+		req.Header.Set(HeaderRequestBudget, strconv.FormatInt(remaining, 10))
+	}
+}
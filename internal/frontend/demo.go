@@ -0,0 +1,183 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+	"github.com/example/spire-workload-demo/internal/scenarios"
+)
+
+// demoError is a demo run that ended without a result from the backend
+type demoError struct {
+	// Problem returned to the API caller
+	problem *api.Problem
+	err     error
+}
+
+func (e *demoError) Error() string { return e.err.Error() }
+func (e *demoError) Unwrap() error { return e.err }
+
+// runDemo runs the demo flow, or the named negative-path scenario, and
+// records the outcome in the history under the correlation ID in ctx
+func (h *Handler) runDemo(ctx context.Context, scenario string) (*DemoResult, error) {
+	start := time.Now()
+	var result *DemoResult
+	var err error
+	if sc, ok := frontendScenarios[scenario]; ok {
+		result, err = h.probeScenario(ctx, sc)
+	} else {
+		result, err = h.callDemo(ctx, scenario)
+	}
+	h.history.add(newDemoRecord(reqctx.CorrelationID(ctx), start, result, err))
+	return result, err
+}
+
+// callDemo calls the backend demo endpoint via Envoy (Pattern 1) with the
+// typed API client, within the frontend's backend budget. A non-empty
+// scenario is run by the backend.
+func (h *Handler) callDemo(ctx context.Context, scenario string) (*DemoResult, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, h.backendTimeout, errBackendBudgetExhausted)
+	defer cancel()
+
+	target := h.backendURL + "/api/demo"
+	h.logger.LogConnectionAttempt(ctx, PatternEnvoySDS, target, h.spiffeID)
+
+	start := time.Now()
+	var result *DemoResult
+	var err error
+	if scenario == "" {
+		result, err = h.backend.Demo(ctx)
+	} else {
+		result, err = h.backend.DemoScenario(ctx, scenario)
+	}
+	if err != nil {
+		return nil, h.demoCallError(ctx, target, err)
+	}
+
+	peerSPIFFEID := "spiffe://example.org/ns/demo/sa/backend"
+	h.logger.LogConnectionSuccess(ctx, PatternEnvoySDS, target, h.spiffeID, peerSPIFFEID)
+
+	result.FrontendToBackend.LatencyMs = float64(time.Since(start).Microseconds()) / 1000
+
+	if result.Scenario != nil {
+		scenarios.Log(ctx, h.logger, result.Scenario)
+		return result, nil
+	}
+
+	h.logger.InfoContext(ctx, "Demo flow completed successfully",
+		"pattern", PatternEnvoySDS,
+		"orders_count", len(result.Orders),
+	)
+	return result, nil
+}
+
+// demoCallError classifies a failed demo call into a demoError
+func (h *Handler) demoCallError(ctx context.Context, target string, err error) error {
+	var apiErr *api.Error
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.StatusCode != http.StatusGatewayTimeout {
+			h.logger.ErrorContext(ctx, "Backend returned error",
+				"status_code", apiErr.StatusCode,
+				"pattern", PatternEnvoySDS,
+			)
+		}
+		return &demoError{problem: wrapUpstream(apiErr), err: err}
+
+	case errors.Is(err, api.ErrInvalidResponse):
+		h.logger.ErrorContext(ctx, "Failed to parse backend response", "error", err.Error())
+		return &demoError{
+			problem: api.NewProblem(api.ProblemBackendError, http.StatusBadGateway,
+				"Failed to parse backend response").WithHop(HopFrontendToBackend),
+			err: fmt.Errorf("failed to parse backend response: %w", err),
+		}
+	}
+
+	h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, target, h.spiffeID, err)
+	if errors.Is(context.Cause(ctx), errBackendBudgetExhausted) {
+		return &demoError{
+			problem: api.NewProblem(api.ProblemDeadlineExceeded, http.StatusGatewayTimeout,
+				"Deadline exceeded on "+HopFrontendToBackend).WithHop(HopFrontendToBackend),
+			err: fmt.Errorf("%w: %v", errBackendBudgetExhausted, err),
+		}
+	}
+	return &demoError{
+		problem: api.NewProblem(api.ProblemBackendUnreachable, http.StatusBadGateway,
+			err.Error()).WithHop(HopFrontendToBackend),
+		err: err,
+	}
+}
+
+// writeDemoError responds to a failed demo run
+func (h *Handler) writeDemoError(w http.ResponseWriter, r *http.Request, err error) {
+	var demoErr *demoError
+	if !errors.As(err, &demoErr) {
+		h.writeProblem(w, r, api.NewProblem(api.ProblemInternal, http.StatusInternalServerError, err.Error()), err)
+		return
+	}
+	h.writeProblem(w, r, demoErr.problem, demoErr.err)
+}
+
+// newDemoRecord builds the history record for a run that returned result
+// or failed with err
+func newDemoRecord(correlationID string, start time.Time, result *DemoResult, err error) DemoRecord {
+	record := DemoRecord{
+		CorrelationID: correlationID,
+		Timestamp:     start,
+		DurationMs:    float64(time.Since(start).Microseconds()) / 1000,
+	}
+
+	if result != nil {
+		record.Result = *result
+		record.OrderCount = len(result.Orders)
+		record.Result.Orders = nil
+		record.Success = result.FrontendToBackend.Success && result.BackendToDatabase.Success
+		if !record.Success {
+			record.FailedHop, record.FailureReason = HopBackendToDatabase, result.BackendToDatabase.Message
+			if !result.FrontendToBackend.Success {
+				record.FailedHop, record.FailureReason = HopFrontendToBackend, result.FrontendToBackend.Message
+			}
+		}
+		return record
+	}
+
+	record.Result.Timestamp = start
+	record.FailureReason = err.Error()
+
+	var demoErr *demoError
+	if errors.As(err, &demoErr) {
+		record.FailureReason = problemSummary(demoErr.problem)
+	}
+
+	// A database failure still means the frontend-to-backend hop worked
+	if demoErr != nil && demoErr.problem.Hop == HopBackendToDatabase {
+		record.FailedHop = HopBackendToDatabase
+		record.Result.FrontendToBackend = ConnectionStatus{
+			Success: true,
+			Message: "Envoy validated frontend SPIFFE ID via SDS",
+			Pattern: PatternEnvoySDS,
+		}
+		record.Result.BackendToDatabase = ConnectionStatus{
+			Message: record.FailureReason,
+			Pattern: PatternSpiffeHelper,
+		}
+		return record
+	}
+
+	record.FailedHop = HopFrontendToBackend
+	record.Result.FrontendToBackend = ConnectionStatus{
+		Message:   record.FailureReason,
+		Pattern:   PatternEnvoySDS,
+		LatencyMs: record.DurationMs,
+	}
+	record.Result.BackendToDatabase = ConnectionStatus{
+		Message: "Unable to determine (frontend-to-backend failed)",
+		Pattern: PatternSpiffeHelper,
+	}
+	return record
+}
@@ -3,22 +3,46 @@ package frontend
 import (
 	"encoding/json"
 	"fmt"
-	"io"
+	"html/template"
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/ratelimit"
 )
 
 // Handler holds dependencies for HTTP handlers
 type Handler struct {
-	logger      *Logger
-	backendURL  string
-	spiffeID    string
-	staticPath  string
+	logger     *Logger
+	backendURL string
+	spiffeID   string
+	// UI assets, embedded unless STATIC_PATH points at a directory
+	assets    *staticAssets
+	templates *template.Template
+	// Total time budget for a backend call, propagated to the backend
+	backendTimeout time.Duration
+	client         *BackendClient
+	// Typed backend API client over client
+	backend *api.Client
+	// Cache in front of GET /api/orders
+	ordersCache *responseCache
+	// Bounded record of demo runs
+	history *demoHistory
+	// Number of history records shown on the dashboard
+	dashboardRuns int
+	// Cached dependency health for readiness, deep health and the dashboard
+	health *healthChecker
+	// Secondary clients for negative-path demo scenarios
+	scenarios *scenarioClients
+	// Runs the demo flow under load for /api/loadtest
+	loadTester *loadTester
+	// Per-route client limits, loaded by StartRateLimiter before serving
+	rateLimits *ratelimit.Service
 }
 
 // NewHandler creates a new handler with dependencies
-func NewHandler(logger *Logger) *Handler {
+func NewHandler(logger *Logger) (*Handler, error) {
 	backendURL := os.Getenv("BACKEND_URL")
 	if backendURL == "" {
 		backendURL = "http://127.0.0.1:8001" // Default: local Envoy proxy
@@ -29,20 +53,56 @@ func NewHandler(logger *Logger) *Handler {
 		spiffeID = "spiffe://example.org/ns/demo/sa/frontend"
 	}
 
-	staticPath := os.Getenv("STATIC_PATH")
-	if staticPath == "" {
-		staticPath = "/app/static"
+	// Empty serves the embedded assets; set a directory to live-edit them
+	assets, err := newStaticAssets(os.Getenv("STATIC_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	// How often new demo runs are written to DEMO_HISTORY_FILE
+	historyFlushInterval := getEnvAsDuration("DEMO_HISTORY_FLUSH_INTERVAL", 2*time.Second)
+	if historyFlushInterval <= 0 {
+		return nil, fmt.Errorf("DEMO_HISTORY_FLUSH_INTERVAL must be positive, got %s", historyFlushInterval)
+	}
+
+	// Keep below the server WriteTimeout so timeouts produce a 504, not a reset
+	backendTimeout := getEnvAsDuration("BACKEND_TIMEOUT", 8*time.Second)
+	if backendTimeout <= 0 {
+		backendTimeout = 8 * time.Second
 	}
 
-	return &Handler{
-		logger:     logger,
-		backendURL: backendURL,
-		spiffeID:   spiffeID,
-		staticPath: staticPath,
+	h := &Handler{
+		logger:         logger,
+		backendURL:     backendURL,
+		spiffeID:       spiffeID,
+		assets:         assets,
+		templates:      parseTemplates(assets),
+		backendTimeout: backendTimeout,
+		client:         NewBackendClient(logger),
+		rateLimits:     newRateLimits(logger),
+		ordersCache: newResponseCache(
+			getEnvAsDuration("ORDERS_CACHE_TTL", 5*time.Second),
+			getEnvAsDuration("ORDERS_CACHE_STALE", 30*time.Second),
+			getEnvAsInt("ORDERS_CACHE_MAX_ENTRIES", 16),
+		),
+		history: newDemoHistory(
+			getEnvAsInt("DEMO_HISTORY_SIZE", 50),
+			os.Getenv("DEMO_HISTORY_FILE"),
+			historyFlushInterval,
+			logger,
+		),
+		dashboardRuns: getEnvAsInt("DASHBOARD_RECENT_DEMOS", 10),
+		scenarios:     newScenarioClients(),
 	}
+	h.backend = api.NewClient(backendURL, h.client)
+	h.backend.UserAgent = "frontend-demo-client"
+	h.health = newHealthChecker(h)
+	h.loadTester = newLoadTester(h)
+	return h, nil
 }
 
-// HealthHandler handles health check requests
+// HealthHandler handles health check requests (liveness). It checks
+// nothing downstream: a backend outage must not restart the frontend.
 func (h *Handler) HealthHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		health := HealthResponse{
@@ -56,104 +116,33 @@ func (h *Handler) HealthHandler() http.HandlerFunc {
 	}
 }
 
-// DemoHandler handles the demo flow - calls backend via Envoy
+// DemoHandler handles the demo flow - calls backend via Envoy. With
+// ?scenario= it runs a negative-path scenario instead.
 func (h *Handler) DemoHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-
-		// Log connection attempt to backend via Envoy (Pattern 1)
-		backendTarget := h.backendURL + "/api/demo"
-		h.logger.LogConnectionAttempt(ctx, PatternEnvoySDS, backendTarget, h.spiffeID)
-
-		// Create HTTP client with timeout
-		client := &http.Client{
-			Timeout: 10 * time.Second,
-		}
-
-		// Call backend via Envoy proxy
-		req, err := http.NewRequestWithContext(ctx, "GET", backendTarget, nil)
-		if err != nil {
-			h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, backendTarget, h.spiffeID, err)
-			http.Error(w, fmt.Sprintf("Failed to create request: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		// Add correlation ID for request tracing
-		correlationID := fmt.Sprintf("demo-%d", time.Now().UnixNano())
-		req.Header.Set("X-Correlation-ID", correlationID)
-		req.Header.Set("User-Agent", "frontend-demo-client")
-
-		// Execute request
-		resp, err := client.Do(req)
-		if err != nil {
-			h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, backendTarget, h.spiffeID, err)
-			http.Error(w, fmt.Sprintf("Backend connection failed: %v", err), http.StatusBadGateway)
+		scenario := r.URL.Query().Get("scenario")
+		if scenario != "" && !knownScenario(scenario) {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest,
+				fmt.Sprintf("Unknown scenario %q", scenario)))
 			return
 		}
-		defer resp.Body.Close()
 
-		// Read response body
-		body, err := io.ReadAll(resp.Body)
+		result, err := h.runDemo(r.Context(), scenario)
 		if err != nil {
-			h.logger.Error("Failed to read backend response", "error", err.Error())
-			http.Error(w, "Failed to read backend response", http.StatusInternalServerError)
+			h.writeDemoError(w, r, err)
 			return
 		}
 
-		// Check if backend returned success
-		if resp.StatusCode != http.StatusOK {
-			h.logger.Error("Backend returned error",
-				"status_code", resp.StatusCode,
-				"pattern", PatternEnvoySDS,
-				"correlation_id", correlationID,
-			)
-			http.Error(w, fmt.Sprintf("Backend error: %s", string(body)), resp.StatusCode)
-			return
-		}
-
-		// Parse backend response
-		var demoResult DemoResult
-		if err := json.Unmarshal(body, &demoResult); err != nil {
-			h.logger.Error("Failed to parse backend response", "error", err.Error())
-			http.Error(w, "Failed to parse backend response", http.StatusInternalServerError)
-			return
-		}
-
-		// Log successful connection to backend via Envoy
-		// The peer SPIFFE ID would be the backend's ID (verified by Envoy)
-		peerSPIFFEID := "spiffe://example.org/ns/demo/sa/backend"
-		h.logger.LogConnectionSuccess(ctx, PatternEnvoySDS, backendTarget, h.spiffeID, peerSPIFFEID)
-		h.logger.Info("Demo flow completed successfully",
-			"pattern", PatternEnvoySDS,
-			"correlation_id", correlationID,
-			"orders_count", len(demoResult.Orders),
-		)
-
 		// Return the full demo result to the UI
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(demoResult)
-	}
-}
-
-// IndexHandler serves the main UI page
-func (h *Handler) IndexHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/" {
-			http.NotFound(w, r)
-			return
-		}
-		http.ServeFile(w, r, h.staticPath+"/index.html")
+		json.NewEncoder(w).Encode(result)
 	}
 }
 
 // StaticHandler serves static assets (CSS, JS)
 func (h *Handler) StaticHandler() http.HandlerFunc {
-	fs := http.FileServer(http.Dir(h.staticPath))
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Strip /static/ prefix before serving
-		http.StripPrefix("/static/", fs).ServeHTTP(w, r)
-	}
+	return h.assets.ServeHTTP
 }
 
 // LoggingMiddleware wraps handlers with request/response logging
@@ -0,0 +1,228 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+)
+
+// Health states for dependencies and the overall deep-health result
+const (
+	HealthHealthy   = "healthy"
+	HealthDegraded  = "degraded"
+	HealthUnhealthy = "unhealthy"
+	HealthUnknown   = "unknown"
+)
+
+// Dependencies probed by the deep health check, in path order
+const (
+	DependencyEnvoy    = "envoy"
+	DependencyBackend  = "backend"
+	DependencyDatabase = "database"
+)
+
+// healthChecker probes the dependency chain and caches the result, so
+// Kubernetes probes and dashboard views don't turn into backend load
+type healthChecker struct {
+	handler  *Handler
+	envoyURL string
+	ttl      time.Duration
+	timeout  time.Duration
+
+	// mu is held while probing; concurrent callers wait and share the result
+	mu       sync.Mutex
+	deps     map[string]*DependencyHealth
+	probedAt time.Time
+}
+
+func newHealthChecker(h *Handler) *healthChecker {
+	c := &healthChecker{
+		handler:  h,
+		envoyURL: getEnv("ENVOY_ADMIN_URL", "http://127.0.0.1:9901"),
+		ttl:      getEnvAsDuration("HEALTH_CACHE_TTL", 5*time.Second),
+		timeout:  getEnvAsDuration("HEALTH_PROBE_TIMEOUT", 2*time.Second),
+		deps:     make(map[string]*DependencyHealth),
+	}
+	for _, name := range []string{DependencyEnvoy, DependencyBackend, DependencyDatabase} {
+		c.deps[name] = &DependencyHealth{Name: name, Status: HealthUnknown}
+	}
+	return c
+}
+
+// check returns the dependency states, probing again if the cached result
+// is older than HEALTH_CACHE_TTL
+func (c *healthChecker) check() (deps []DependencyHealth, cached bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached = !c.probedAt.IsZero() && time.Since(c.probedAt) < c.ttl
+	if !cached {
+		c.probe()
+		c.probedAt = time.Now()
+	}
+	return c.snapshot(), cached
+}
+
+// last returns the cached dependency states without probing
+func (c *healthChecker) last() []DependencyHealth {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshot()
+}
+
+func (c *healthChecker) snapshot() []DependencyHealth {
+	deps := make([]DependencyHealth, 0, len(c.deps))
+	for _, name := range []string{DependencyEnvoy, DependencyBackend, DependencyDatabase} {
+		deps = append(deps, *c.deps[name])
+	}
+	return deps
+}
+
+// probe checks Envoy's admin /ready, then the backend's /health through
+// Envoy. The backend's answer also covers the database. Probes are not
+// retried so the recorded latency is a single round trip.
+func (c *healthChecker) probe() {
+	// Probes outlive no request; a client disconnect must not fail them.
+	// One correlation ID covers the whole probe round.
+	ctx, cancel := context.WithTimeout(reqctx.WithCorrelationID(context.Background(), reqctx.NewCorrelationID()), c.timeout)
+	defer cancel()
+
+	status, _, latency, err := c.get(ctx, c.envoyURL+"/ready")
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("envoy admin returned %d", status)
+	}
+	c.record(DependencyEnvoy, latency, err)
+
+	status, body, latency, err := c.get(ctx, c.handler.backendURL+"/health")
+	if err != nil {
+		c.record(DependencyBackend, latency, err)
+		c.deps[DependencyDatabase].Status = HealthUnknown
+		return
+	}
+
+	var health HealthResponse
+	json.Unmarshal(body, &health)
+
+	switch {
+	case status == http.StatusOK && health.Status == "healthy":
+		c.record(DependencyBackend, latency, nil)
+		c.record(DependencyDatabase, time.Duration(health.DatabaseLatencyMs*float64(time.Millisecond)), nil)
+	case status == http.StatusOK:
+		c.record(DependencyBackend, latency, nil)
+		c.record(DependencyDatabase, 0, fmt.Errorf("backend is %s: database connection not established yet", health.Status))
+	case status == http.StatusServiceUnavailable:
+		c.record(DependencyBackend, latency, nil)
+		c.record(DependencyDatabase, 0, fmt.Errorf("backend reports: %s", strings.TrimSpace(string(body))))
+	default:
+		// e.g. 403 from the backend Envoy's RBAC filter
+		c.record(DependencyBackend, latency, fmt.Errorf("backend returned %d: %s", status, strings.TrimSpace(string(body))))
+		c.deps[DependencyDatabase].Status = HealthUnknown
+	}
+}
+
+// get sends a single GET on the shared backend connection pool
+func (c *healthChecker) get(ctx context.Context, url string) (int, []byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, nil, 0, err
+	}
+	req.Header.Set("User-Agent", "frontend-health-probe")
+
+	start := time.Now()
+	resp, err := c.handler.client.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, time.Since(start), err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, body, time.Since(start), err
+}
+
+func (c *healthChecker) record(name string, latency time.Duration, err error) {
+	dep := c.deps[name]
+	now := time.Now()
+
+	dep.CheckedAt = now
+	dep.LatencyMs = float64(latency.Microseconds()) / 1000
+	if err == nil {
+		dep.Status = HealthHealthy
+		return
+	}
+
+	if dep.Status != HealthUnhealthy {
+		c.handler.logger.Error("Dependency unhealthy", "dependency", name, "error", err.Error())
+	}
+	dep.Status = HealthUnhealthy
+	dep.LastError = err.Error()
+	dep.LastErrorAt = &now
+}
+
+// dependency returns the named entry from a check result
+func dependency(deps []DependencyHealth, name string) DependencyHealth {
+	for _, dep := range deps {
+		if dep.Name == name {
+			return dep
+		}
+	}
+	return DependencyHealth{Name: name, Status: HealthUnknown}
+}
+
+// ReadyHandler handles GET /ready (readiness): ready once the backend
+// answers through Envoy. A database outage alone does not take the
+// frontend out of rotation; the dashboard reports it.
+func (h *Handler) ReadyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deps, _ := h.health.check()
+		backend := dependency(deps, DependencyBackend)
+
+		if backend.Status != HealthHealthy {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemBackendUnreachable, http.StatusServiceUnavailable,
+				"Backend unreachable through Envoy: "+backend.LastError).WithHop(HopFrontendToBackend))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(HealthResponse{
+			Component: "frontend",
+			Status:    "ready",
+		})
+	}
+}
+
+// DeepHealthHandler handles GET /health/deep: status, latency and last
+// error per dependency. It responds 503 only when the backend is unreachable.
+func (h *Handler) DeepHealthHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deps, cached := h.health.check()
+
+		response := DeepHealthResponse{
+			Component:    "frontend",
+			Status:       HealthHealthy,
+			Cached:       cached,
+			Dependencies: deps,
+		}
+		for _, dep := range deps {
+			if dep.Status != HealthHealthy {
+				response.Status = HealthDegraded
+			}
+		}
+		if dependency(deps, DependencyBackend).Status != HealthHealthy {
+			response.Status = HealthUnhealthy
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if response.Status == HealthUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(response)
+	}
+}
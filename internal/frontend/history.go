@@ -0,0 +1,253 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// DemoRecord is one demo run kept in the history
+type DemoRecord struct {
+	CorrelationID string    `json:"correlation_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	// End-to-end duration observed by the frontend
+	DurationMs float64 `json:"duration_ms"`
+	Success    bool    `json:"success"`
+	// Hop that failed first and why; empty on success
+	FailedHop     string `json:"failed_hop,omitempty"`
+	FailureReason string `json:"failure_reason,omitempty"`
+	// Number of orders the run read; the orders themselves are not kept
+	OrderCount int `json:"order_count"`
+	// Backend result without its orders, or per-hop statuses reconstructed
+	// from the failure
+	Result DemoResult `json:"result"`
+}
+
+// demoHistory is a bounded ring buffer of demo runs, optionally mirrored to
+// a JSON file so history survives restarts. The file is rewritten in the
+// background at most once per flushInterval, not on every run.
+type demoHistory struct {
+	logger *Logger
+	// DEMO_HISTORY_FILE; empty keeps history in memory only
+	path          string
+	flushInterval time.Duration
+	// Set by add when records have not been written to path yet
+	dirty atomic.Bool
+
+	mu      sync.RWMutex
+	records []DemoRecord
+	// Index the next record is written to
+	next  int
+	count int
+
+	// Serializes file writes, which happen outside mu
+	persistMu sync.Mutex
+}
+
+// newDemoHistory creates a history holding up to size records and loads
+// any records persisted at path
+func newDemoHistory(size int, path string, flushInterval time.Duration, logger *Logger) *demoHistory {
+	if size < 1 {
+		size = 1
+	}
+	h := &demoHistory{
+		logger:        logger,
+		path:          path,
+		flushInterval: flushInterval,
+		records:       make([]DemoRecord, size),
+	}
+
+	if path != "" {
+		if err := h.load(); err != nil {
+			logger.Error("Failed to load demo history", "path", path, "error", err.Error())
+		}
+	}
+	return h
+}
+
+// add stores a record, evicting the oldest when full. Writing it to the
+// file is left to run.
+func (h *demoHistory) add(record DemoRecord) {
+	h.mu.Lock()
+	h.push(record)
+	h.mu.Unlock()
+
+	if h.path != "" {
+		h.dirty.Store(true)
+	}
+}
+
+// run writes added records to the file every flushInterval until ctx is
+// cancelled, coalescing the runs added in between into one write
+func (h *demoHistory) run(ctx context.Context) {
+	if h.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(h.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.flush()
+		}
+	}
+}
+
+// flush writes the history to the file if records were added since the
+// last write. A failed write is retried on the next flush.
+func (h *demoHistory) flush() {
+	if !h.dirty.Swap(false) {
+		return
+	}
+	if err := h.persist(); err != nil {
+		h.dirty.Store(true)
+		h.logger.Error("Failed to persist demo history", "path", h.path, "error", err.Error())
+	}
+}
+
+func (h *demoHistory) push(record DemoRecord) {
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.count < len(h.records) {
+		h.count++
+	}
+}
+
+// list returns up to limit records, newest first; limit <= 0 returns all
+func (h *demoHistory) list(limit int) []DemoRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if limit <= 0 || limit > h.count {
+		limit = h.count
+	}
+	records := make([]DemoRecord, 0, limit)
+	for i := 1; i <= limit; i++ {
+		records = append(records, h.records[(h.next-i+len(h.records))%len(h.records)])
+	}
+	return records
+}
+
+// get returns the record with the given correlation ID
+func (h *demoHistory) get(correlationID string) (DemoRecord, bool) {
+	for _, record := range h.list(0) {
+		if record.CorrelationID == correlationID {
+			return record, true
+		}
+	}
+	return DemoRecord{}, false
+}
+
+// persist writes the history to a temporary file and renames it over path,
+// so a crash never leaves a truncated file behind
+func (h *demoHistory) persist() error {
+	h.persistMu.Lock()
+	defer h.persistMu.Unlock()
+
+	data, err := json.Marshal(h.list(0))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), filepath.Base(h.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), h.path)
+}
+
+func (h *demoHistory) load() error {
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var records []DemoRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("invalid history file: %w", err)
+	}
+
+	// The file is newest first; replay oldest first. Files written before
+	// records dropped their orders still carry them.
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(records) - 1; i >= 0; i-- {
+		if orders := records[i].Result.Orders; orders != nil {
+			records[i].OrderCount = len(orders)
+			records[i].Result.Orders = nil
+		}
+		h.push(records[i])
+	}
+
+	h.logger.Info("Loaded demo history", "path", h.path, "records", h.count)
+	return nil
+}
+
+// StartHistory writes demo runs to DEMO_HISTORY_FILE in the background
+// until ctx is cancelled
+func (h *Handler) StartHistory(ctx context.Context) {
+	go h.history.run(ctx)
+}
+
+// FlushHistory writes the demo runs not yet in DEMO_HISTORY_FILE; call it
+// on shutdown
+func (h *Handler) FlushHistory() {
+	h.history.flush()
+}
+
+// DemoHistoryHandler handles GET /api/demo/history, newest first. The
+// optional limit parameter caps the number of records.
+func (h *Handler) DemoHistoryHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid limit"))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.history.list(limit))
+	}
+}
+
+// DemoHistoryRecordHandler handles GET /api/demo/history/{correlation_id}
+func (h *Handler) DemoHistoryRecordHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		record, ok := h.history.get(r.PathValue("correlation_id"))
+		if !ok {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "Demo run not found in history"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(record)
+	}
+}
@@ -0,0 +1,460 @@
+package frontend
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+)
+
+// maxLoadTestBodyBytes bounds POST /api/loadtest bodies
+const maxLoadTestBodyBytes = 4 << 10
+
+// loadTester runs the demo flow at a target rate and concurrency, one load
+// test at a time. Runs bypass the demo history and go through the same
+// backend client, Envoy and database as the dashboard's runs.
+type loadTester struct {
+	handler *Handler
+	// Upper bounds on what a request may ask for
+	maxRate        int
+	maxConcurrency int
+	maxDuration    time.Duration
+
+	mu     sync.Mutex
+	latest *loadTest
+}
+
+func newLoadTester(h *Handler) *loadTester {
+	return &loadTester{
+		handler:        h,
+		maxRate:        getEnvAsInt("LOADTEST_MAX_RATE", 200),
+		maxConcurrency: getEnvAsInt("LOADTEST_MAX_CONCURRENCY", 50),
+		maxDuration:    getEnvAsDuration("LOADTEST_MAX_DURATION", 5*time.Minute),
+	}
+}
+
+// loadSample is one demo run of a load test. Latencies are in
+// milliseconds; zero means the hop was not reached.
+type loadSample struct {
+	// Offset of the run's start from the start of the test
+	offset   time.Duration
+	endToEnd float64
+	fe2be    float64
+	be2db    float64
+	failed   bool
+	// Pool wait the backend reported on the database hop; poolReported is
+	// false when the run never got a database hop result from the backend
+	poolWait     float64
+	poolReported bool
+}
+
+type loadErrorKey struct {
+	pattern, hop, reason string
+}
+
+// loadTest is one load test run and its accumulated results
+type loadTest struct {
+	request  api.LoadTestRequest
+	duration time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu         sync.Mutex
+	state      string
+	startedAt  time.Time
+	finishedAt time.Time
+	samples    []loadSample
+	skipped    int64
+	errors     map[loadErrorKey]*api.LoadTestError
+}
+
+// LoadTestStartHandler handles POST /api/loadtest: it starts a load test in
+// the background and answers 202 with its initial report. Poll
+// GET /api/loadtest for progress.
+func (h *Handler) LoadTestStartHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req api.LoadTestRequest
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLoadTestBodyBytes))
+		if err == nil {
+			err = json.Unmarshal(body, &req)
+		}
+		if err != nil {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid request body: "+err.Error()))
+			return
+		}
+
+		test, err := h.loadTester.start(req)
+		if err != nil {
+			var problem *api.Problem
+			if !errors.As(err, &problem) {
+				problem = api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, err.Error())
+			}
+			api.WriteProblem(w, r, problem)
+			return
+		}
+
+		h.logger.InfoContext(r.Context(), "Load test started",
+			"rate", req.Rate,
+			"concurrency", req.Concurrency,
+			"duration", req.Duration,
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/api/loadtest")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(test.report())
+	}
+}
+
+// LoadTestHandler handles GET /api/loadtest: the running or most recent
+// load test
+func (h *Handler) LoadTestHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		test := h.loadTester.current()
+		if test == nil {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "No load test has run yet"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(test.report())
+	}
+}
+
+// LoadTestStopHandler handles DELETE /api/loadtest: it cancels a running
+// load test, waits for its in-flight runs and returns the final report
+func (h *Handler) LoadTestStopHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		test := h.loadTester.current()
+		if test == nil {
+			api.WriteProblem(w, r, api.NewProblem(api.ProblemNotFound, http.StatusNotFound, "No load test has run yet"))
+			return
+		}
+
+		test.cancel()
+		select {
+		case <-test.done:
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(test.report())
+	}
+}
+
+// start validates req and starts a load test unless one is running
+func (l *loadTester) start(req api.LoadTestRequest) (*loadTest, error) {
+	duration, err := time.ParseDuration(req.Duration)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("invalid duration: %w", err)
+	case duration <= 0 || duration > l.maxDuration:
+		return nil, fmt.Errorf("duration must be positive and at most %s", l.maxDuration)
+	case req.Rate <= 0 || req.Rate > float64(l.maxRate):
+		return nil, fmt.Errorf("rate must be positive and at most %d per second", l.maxRate)
+	case req.Concurrency < 1 || req.Concurrency > l.maxConcurrency:
+		return nil, fmt.Errorf("concurrency must be between 1 and %d", l.maxConcurrency)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.latest != nil && l.latest.running() {
+		return nil, api.NewProblem(api.ProblemLoadTestRunning, http.StatusConflict,
+			"Cancel it with DELETE /api/loadtest or wait for it to finish")
+	}
+
+	// Not tied to the request that started it
+	ctx, cancel := context.WithCancel(context.Background())
+	test := &loadTest{
+		request:   req,
+		duration:  duration,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		state:     api.LoadTestRunning,
+		startedAt: time.Now(),
+		errors:    make(map[loadErrorKey]*api.LoadTestError),
+	}
+	l.latest = test
+	go l.run(ctx, test)
+	return test, nil
+}
+
+func (l *loadTester) current() *loadTest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.latest
+}
+
+// run starts a demo run every 1/rate seconds on one of concurrency workers
+// until the duration has passed or the test is cancelled
+func (l *loadTester) run(ctx context.Context, test *loadTest) {
+	defer close(test.done)
+	defer test.cancel()
+	h := l.handler
+
+	runs := make(chan time.Time)
+	var workers sync.WaitGroup
+	for range test.request.Concurrency {
+		workers.Go(func() {
+			for due := range runs {
+				sample, key, message := l.runOnce(ctx, due.Sub(test.startedAt))
+				// Runs cut short by a cancel say nothing about the services
+				if ctx.Err() != nil {
+					continue
+				}
+				test.record(sample, key, message)
+			}
+		})
+	}
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / test.request.Rate))
+	timer := time.NewTimer(test.duration)
+	state := api.LoadTestCompleted
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			state = api.LoadTestCancelled
+			break loop
+		case <-timer.C:
+			break loop
+		case due := <-ticker.C:
+			select {
+			case runs <- due:
+			default:
+				test.mu.Lock()
+				test.skipped++
+				test.mu.Unlock()
+			}
+		}
+	}
+	ticker.Stop()
+	timer.Stop()
+	close(runs)
+	workers.Wait()
+
+	test.mu.Lock()
+	test.state = state
+	test.finishedAt = time.Now()
+	test.mu.Unlock()
+
+	report := test.report()
+	h.logger.Info("Load test finished",
+		"state", report.State,
+		"requests", report.Requests,
+		"failures", report.Failures,
+		"skipped", report.Skipped,
+		"achieved_rate", report.AchievedRate,
+		"p99_ms", report.Latency.EndToEnd.P99,
+	)
+}
+
+// runOnce runs the demo flow under its own correlation ID and classifies
+// a failure by the pattern and hop that failed
+func (l *loadTester) runOnce(ctx context.Context, offset time.Duration) (loadSample, loadErrorKey, string) {
+	h := l.handler
+	id := reqctx.NewCorrelationID()
+	ctx = reqctx.WithCorrelationID(ctx, id)
+
+	start := time.Now()
+	result, err := h.callDemo(ctx, "")
+	record := newDemoRecord(id, start, result, err)
+
+	sample := loadSample{
+		offset:   offset,
+		endToEnd: record.DurationMs,
+		fe2be:    record.Result.FrontendToBackend.LatencyMs,
+		be2db:    record.Result.BackendToDatabase.LatencyMs,
+		failed:   !record.Success,
+		// Each backend replica reports its own wait, so runs answered by
+		// different pods add up without mixing their pool counters
+		poolWait:     record.Result.BackendToDatabase.PoolWaitMs,
+		poolReported: result != nil,
+	}
+	if record.Success {
+		return sample, loadErrorKey{}, ""
+	}
+
+	key := loadErrorKey{hop: record.FailedHop, pattern: record.Result.FrontendToBackend.Pattern}
+	if record.FailedHop == HopBackendToDatabase {
+		key.pattern = record.Result.BackendToDatabase.Pattern
+	}
+
+	var demoErr *demoError
+	switch {
+	case errors.As(err, &demoErr):
+		key.reason = path.Base(demoErr.problem.Type)
+	case record.Result.BackendToDatabase.CircuitState == "open":
+		key.reason = "circuit-open"
+	default:
+		key.reason = "database-error"
+	}
+	return sample, key, record.FailureReason
+}
+
+func (t *loadTest) running() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state == api.LoadTestRunning
+}
+
+func (t *loadTest) record(sample loadSample, key loadErrorKey, message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples = append(t.samples, sample)
+	if !sample.failed {
+		return
+	}
+	count := t.errors[key]
+	if count == nil {
+		count = &api.LoadTestError{Pattern: key.pattern, Hop: key.hop, Reason: key.reason}
+		t.errors[key] = count
+	}
+	count.Count++
+	count.Example = message
+}
+
+// report summarizes the samples so far
+func (t *loadTest) report() *api.LoadTestReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	report := &api.LoadTestReport{
+		State:     t.state,
+		Request:   t.request,
+		StartedAt: t.startedAt,
+		Requests:  int64(len(t.samples)),
+		Skipped:   t.skipped,
+		Errors:    make([]api.LoadTestError, 0, len(t.errors)),
+		Timeline:  []api.LoadTestSecond{},
+	}
+
+	elapsed := time.Since(t.startedAt)
+	if !t.finishedAt.IsZero() {
+		finished := t.finishedAt
+		report.FinishedAt = &finished
+		elapsed = finished.Sub(t.startedAt)
+	}
+	report.ElapsedMs = float64(elapsed.Microseconds()) / 1000
+	if elapsed > 0 {
+		report.AchievedRate = float64(len(t.samples)) / elapsed.Seconds()
+	}
+
+	var endToEnd, fe2be, be2db []float64
+	var wait api.PoolWait
+	for _, sample := range t.samples {
+		if sample.poolReported {
+			wait.Runs++
+			if sample.poolWait > 0 {
+				wait.WaitCount++
+				wait.WaitDurationMS += sample.poolWait
+				wait.MaxWaitMS = max(wait.MaxWaitMS, sample.poolWait)
+			}
+		}
+		if sample.failed {
+			report.Failures++
+		}
+		endToEnd = append(endToEnd, sample.endToEnd)
+		if sample.fe2be > 0 {
+			fe2be = append(fe2be, sample.fe2be)
+		}
+		if sample.be2db > 0 {
+			be2db = append(be2db, sample.be2db)
+		}
+	}
+	report.Successes = report.Requests - report.Failures
+	if wait.Runs > 0 {
+		if wait.WaitCount > 0 {
+			wait.MeanWaitMS = wait.WaitDurationMS / float64(wait.WaitCount)
+		}
+		report.PoolWait = &wait
+	} else if len(t.samples) > 0 {
+		report.PoolWaitError = "no demo run got a database result from the backend"
+	}
+	report.Latency = api.LoadTestLatency{
+		EndToEnd:          summarize(endToEnd),
+		FrontendToBackend: summarize(fe2be),
+		BackendToDatabase: summarize(be2db),
+	}
+
+	for _, count := range t.errors {
+		report.Errors = append(report.Errors, *count)
+	}
+	slices.SortFunc(report.Errors, func(a, b api.LoadTestError) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+
+	report.Timeline = timeline(t.samples)
+	return report
+}
+
+// timeline groups samples by the second their run started in
+func timeline(samples []loadSample) []api.LoadTestSecond {
+	seconds := []api.LoadTestSecond{}
+	var latencies []float64
+	flush := func() {
+		last := &seconds[len(seconds)-1]
+		summary := summarize(latencies)
+		last.P50, last.P99 = summary.P50, summary.P99
+		latencies = latencies[:0]
+	}
+
+	// Workers finish out of order; sort a copy by start time
+	sorted := slices.Clone(samples)
+	slices.SortFunc(sorted, func(a, b loadSample) int {
+		return cmp.Compare(a.offset, b.offset)
+	})
+	for _, sample := range sorted {
+		second := int(sample.offset / time.Second)
+		if len(seconds) == 0 || seconds[len(seconds)-1].Second != second {
+			if len(seconds) > 0 {
+				flush()
+			}
+			seconds = append(seconds, api.LoadTestSecond{Second: second})
+		}
+		last := &seconds[len(seconds)-1]
+		last.Requests++
+		if sample.failed {
+			last.Failures++
+		}
+		latencies = append(latencies, sample.endToEnd)
+	}
+	if len(seconds) > 0 {
+		flush()
+	}
+	return seconds
+}
+
+// summarize returns nearest-rank percentiles of values
+func summarize(values []float64) api.LatencySummary {
+	if len(values) == 0 {
+		return api.LatencySummary{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	rank := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return api.LatencySummary{
+		Count: len(sorted),
+		P50:   rank(0.50),
+		P90:   rank(0.90),
+		P95:   rank(0.95),
+		P99:   rank(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
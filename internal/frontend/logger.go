@@ -4,12 +4,20 @@ import (
 	"context"
 	"log/slog"
 	"os"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/ratelimit"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+	"github.com/example/spire-workload-demo/internal/scenarios"
+	"github.com/example/spire-workload-demo/internal/topology"
 )
 
 // Pattern identifiers for SPIFFE integration patterns (FR-019)
-// Frontend only uses Envoy SDS (Pattern 1)
+// Frontend only uses Envoy SDS (Pattern 1); spiffe-helper (Pattern 2) is
+// reported for the backend's database hop
 const (
-	PatternEnvoySDS = "envoy-sds"
+	PatternEnvoySDS     = "envoy-sds"
+	PatternSpiffeHelper = "spiffe-helper"
 )
 
 // Event types for structured logging
@@ -18,12 +26,21 @@ const (
 	EventConnectionSuccess = "connection_success"
 	EventConnectionFailure = "connection_failure"
 	EventHTTPRequest       = "http_request"
+	EventDeadlineExceeded  = "deadline_exceeded"
+	EventBackendRetry      = "backend_retry"
+	EventCacheLookup       = "cache_lookup"
+	EventOpenAPIViolation  = "openapi_violation"
+	EventScenarioResult    = scenarios.EventResult
+	EventRateLimited       = ratelimit.EventLimited
+	EventRateLimitReload   = ratelimit.EventReload
 )
 
 // Logger wraps slog with structured fields for pattern-aware logging
 type Logger struct {
 	logger    *slog.Logger
 	component string
+	// Connections observed through the logged connection events
+	topology *topology.Recorder
 }
 
 // NewLogger creates a new structured logger for the frontend component
@@ -45,12 +62,20 @@ func NewLogger(component string) *Logger {
 		})
 	}
 
+	// Every ctx-aware call picks up the request's correlation ID
+	recorder := topology.NewRecorder(topology.Observer(component))
 	return &Logger{
-		logger:    slog.New(handler),
+		logger:    slog.New(reqctx.NewLogHandler(topology.NewLogHandler(handler, recorder))),
 		component: component,
+		topology:  recorder,
 	}
 }
 
+// Topology returns the connections logged so far as a graph
+func (l *Logger) Topology() *api.Topology {
+	return l.topology.Graph()
+}
+
 // LogEvent logs a structured event with pattern identifier and SPIFFE context
 func (l *Logger) LogEvent(ctx context.Context, pattern, event, spiffeID, peerSPIFFEID, message string) {
 	l.logger.InfoContext(ctx,
@@ -123,3 +148,18 @@ func (l *Logger) Info(message string, args ...any) {
 func (l *Logger) Error(message string, args ...any) {
 	l.logger.Error(message, append([]any{"component", l.component}, args...)...)
 }
+
+// InfoContext logs an informational message with the correlation ID in ctx
+func (l *Logger) InfoContext(ctx context.Context, message string, args ...any) {
+	l.logger.InfoContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
+
+// WarnContext logs a warning message with the correlation ID in ctx
+func (l *Logger) WarnContext(ctx context.Context, message string, args ...any) {
+	l.logger.WarnContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
+
+// ErrorContext logs an error message with the correlation ID in ctx
+func (l *Logger) ErrorContext(ctx context.Context, message string, args ...any) {
+	l.logger.ErrorContext(ctx, message, append([]any{"component", l.component}, args...)...)
+}
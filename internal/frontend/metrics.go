@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// MetricsHandler handles GET /metrics requests in the Prometheus text format
+func (h *Handler) MetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		requests, retries := h.client.Stats()
+		writeMetric(w, "frontend_backend_requests_total", "counter", "Backend request attempts, including retries.", float64(requests))
+		writeMetric(w, "frontend_backend_retries_total", "counter", "Backend request attempts that were retries.", float64(retries))
+
+		hits, stales, misses := h.ordersCache.stats()
+		writeMetric(w, "frontend_orders_cache_hits_total", "counter", "Order list requests served fresh from the cache.", float64(hits))
+		writeMetric(w, "frontend_orders_cache_stale_total", "counter", "Order list requests served stale while revalidating.", float64(stales))
+		writeMetric(w, "frontend_orders_cache_misses_total", "counter", "Order list requests forwarded to the backend.", float64(misses))
+
+		// Last cached probe results; scraping does not trigger a probe
+		fmt.Fprintf(w, "# HELP frontend_dependency_up Whether the dependency passed its last health probe.\n# TYPE frontend_dependency_up gauge\n")
+		for _, dep := range h.health.last() {
+			up := 0
+			if dep.Status == HealthHealthy {
+				up = 1
+			}
+			fmt.Fprintf(w, "frontend_dependency_up{dependency=%q} %d\n", dep.Name, up)
+		}
+
+		h.rateLimits.WriteMetrics(w)
+	}
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %g\n", name, help, name, kind, name, value)
+}
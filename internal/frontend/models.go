@@ -1,33 +1,34 @@
 package frontend
 
-import "time"
+import (
+	"time"
 
-// Order represents a demo order entity (mirrors backend structure)
-type Order struct {
-	ID          int       `json:"id"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-}
+	"github.com/example/spire-workload-demo/api"
+)
 
-// ConnectionStatus represents the status of a connection attempt
-type ConnectionStatus struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
-	Pattern string `json:"pattern"` // "envoy-sds" or "spiffe-helper"
-}
+// Backend wire types come from the api package
+type (
+	Order            = api.Order
+	ConnectionStatus = api.ConnectionStatus
+	DemoResult       = api.DemoResult
+	HealthResponse   = api.HealthResponse
+)
 
-// DemoResult represents the result of the full demo flow
-// This mirrors the backend DemoResult structure
-type DemoResult struct {
-	FrontendToBackend ConnectionStatus `json:"frontend_to_backend"`
-	BackendToDatabase ConnectionStatus `json:"backend_to_database"`
-	Orders            []Order          `json:"orders,omitempty"`
-	Timestamp         time.Time        `json:"timestamp"`
+// DependencyHealth is the latest probe result for one dependency
+type DependencyHealth struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	LatencyMs float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Most recent failure, kept after the dependency recovers
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
 }
 
-// HealthResponse represents the health check response
-type HealthResponse struct {
-	Component string `json:"component"`
-	Status    string `json:"status"`
+// DeepHealthResponse is returned by /health/deep
+type DeepHealthResponse struct {
+	Component    string             `json:"component"`
+	Status       string             `json:"status"`
+	Cached       bool               `json:"cached"`
+	Dependencies []DependencyHealth `json:"dependencies"`
 }
@@ -0,0 +1,46 @@
+package frontend
+
+import (
+	"errors"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/api/openapi"
+)
+
+// OpenAPITypes returns the frontend's wire types keyed by the component schema
+// they are sent as
+func OpenAPITypes() map[string]any {
+	return map[string]any{
+		"Order":              Order{},
+		"CreateOrderRequest": api.CreateOrderRequest{},
+		"UpdateOrderRequest": api.UpdateOrderRequest{},
+		"ConnectionStatus":   ConnectionStatus{},
+		"DemoResult":         DemoResult{},
+		"DemoRecord":         DemoRecord{},
+		"HealthResponse":     HealthResponse{},
+		"DependencyHealth":   DependencyHealth{},
+		"DeepHealthResponse": DeepHealthResponse{},
+		"Problem":            api.Problem{},
+		"ScenarioReport":     api.ScenarioReport{},
+		"Topology":           api.Topology{},
+		"TopologyNode":       api.TopologyNode{},
+		"TopologyEdge":       api.TopologyEdge{},
+		"LoadTestRequest":    api.LoadTestRequest{},
+		"LoadTestReport":     api.LoadTestReport{},
+		"LoadTestLatency":    api.LoadTestLatency{},
+		"LatencySummary":     api.LatencySummary{},
+		"LoadTestError":      api.LoadTestError{},
+		"LoadTestSecond":     api.LoadTestSecond{},
+		"PoolWait":           api.PoolWait{},
+	}
+}
+
+// CheckOpenAPI verifies that the frontend's wire types match their schemas
+// in the OpenAPI document
+func CheckOpenAPI(doc *openapi.Document) error {
+	var errs []error
+	for name, v := range OpenAPITypes() {
+		errs = append(errs, doc.CheckType(name, v))
+	}
+	return errors.Join(errs...)
+}
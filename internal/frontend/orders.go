@@ -0,0 +1,189 @@
+package frontend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// maxOrderBodyBytes bounds order bodies forwarded to the backend
+const maxOrderBodyBytes = 64 << 10
+
+// HeaderCache reports whether a list response came from the orders cache
+const HeaderCache = "X-Cache"
+
+// ListOrdersHandler handles GET /api/orders. Responses are cached for
+// ORDERS_CACHE_TTL and served stale for up to ORDERS_CACHE_STALE longer
+// while a background request refreshes them.
+func (h *Handler) ListOrdersHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// read_your_writes is the only parameter the backend reads; others
+		// are dropped so they cannot create cache entries. A caller asking
+		// to read its own writes must not get a cached copy.
+		if pinned, _ := strconv.ParseBool(r.URL.Query().Get("read_your_writes")); pinned {
+			h.proxyOrders(w, r, http.MethodGet, "/api/orders?read_your_writes=true", nil)
+			return
+		}
+		key := "/api/orders"
+
+		cached, result, refresh := h.ordersCache.get(key)
+		h.logger.InfoContext(r.Context(), "Orders cache lookup",
+			"event", EventCacheLookup,
+			"result", result,
+			"key", key,
+		)
+		if cached != nil {
+			if refresh {
+				go h.refreshOrders(context.WithoutCancel(r.Context()), key)
+			}
+			h.writeBackendResponse(w, r, cached, result)
+			return
+		}
+
+		gen := h.ordersCache.generation()
+		resp, err := h.fetchBackend(r.Context(), http.MethodGet, key, nil)
+		if err != nil {
+			h.writeBackendError(w, r, err)
+			return
+		}
+		if resp.status == http.StatusOK {
+			h.ordersCache.set(key, resp, gen)
+		}
+		h.writeBackendResponse(w, r, resp, CacheMiss)
+	}
+}
+
+// GetOrderHandler handles GET /api/orders/{id}
+func (h *Handler) GetOrderHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		h.proxyOrders(w, r, http.MethodGet, "/api/orders/"+id, nil)
+	}
+}
+
+// CreateOrderHandler handles POST /api/orders
+func (h *Handler) CreateOrderHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, ok := readOrderBody(w, r)
+		if !ok {
+			return
+		}
+		h.proxyOrders(w, r, http.MethodPost, "/api/orders", body)
+	}
+}
+
+// UpdateOrderHandler handles PUT /api/orders/{id}
+func (h *Handler) UpdateOrderHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := orderID(w, r)
+		if !ok {
+			return
+		}
+		body, ok := readOrderBody(w, r)
+		if !ok {
+			return
+		}
+		h.proxyOrders(w, r, http.MethodPut, "/api/orders/"+id, body)
+	}
+}
+
+// proxyOrders forwards a request to the backend uncached. A successful write
+// invalidates the orders cache.
+func (h *Handler) proxyOrders(w http.ResponseWriter, r *http.Request, method, path string, body []byte) {
+	resp, err := h.fetchBackend(r.Context(), method, path, body)
+	if err != nil {
+		h.writeBackendError(w, r, err)
+		return
+	}
+
+	if method != http.MethodGet && resp.status < 300 {
+		h.ordersCache.invalidate()
+		h.logger.InfoContext(r.Context(), "Orders cache invalidated",
+			"method", method,
+			"path", path,
+		)
+	}
+	h.writeBackendResponse(w, r, resp, "")
+}
+
+// refreshOrders replaces a stale cache entry in the background. It runs
+// after the triggering request completed, so ctx must not be cancelled with
+// it; it keeps the request's correlation ID and gets its own budget.
+func (h *Handler) refreshOrders(ctx context.Context, key string) {
+	gen := h.ordersCache.generation()
+	resp, err := h.fetchBackend(ctx, http.MethodGet, key, nil)
+	if err == nil && resp.status != http.StatusOK {
+		err = fmt.Errorf("backend returned %d", resp.status)
+	}
+	if err != nil {
+		h.ordersCache.abortRefresh(key)
+		h.logger.ErrorContext(ctx, "Orders cache refresh failed",
+			"key", key,
+			"error", err.Error(),
+		)
+		return
+	}
+
+	h.ordersCache.set(key, resp, gen)
+	h.logger.InfoContext(ctx, "Orders cache refreshed", "key", key)
+}
+
+// writeBackendResponse replays a successful backend response to the
+// browser; errors are wrapped in the frontend's own problem
+func (h *Handler) writeBackendResponse(w http.ResponseWriter, r *http.Request, resp *backendResponse, cache string) {
+	if resp.status >= http.StatusBadRequest {
+		apiErr := api.NewError(resp.status, resp.header, resp.body)
+		h.writeProblem(w, r, wrapUpstream(apiErr), apiErr)
+		return
+	}
+
+	for _, name := range []string{"Content-Type", "Location"} {
+		if value := resp.header.Get(name); value != "" {
+			w.Header().Set(name, value)
+		}
+	}
+	if cache != "" {
+		w.Header().Set(HeaderCache, cache)
+	}
+	w.WriteHeader(resp.status)
+	w.Write(resp.body)
+}
+
+// writeBackendError responds to a request that never got a backend response
+func (h *Handler) writeBackendError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errBackendBudgetExhausted) {
+		h.writeProblem(w, r, api.NewProblem(api.ProblemDeadlineExceeded, http.StatusGatewayTimeout,
+			"Deadline exceeded on "+HopFrontendToBackend).WithHop(HopFrontendToBackend), err)
+		return
+	}
+	h.writeProblem(w, r, api.NewProblem(api.ProblemBackendUnreachable, http.StatusBadGateway,
+		err.Error()).WithHop(HopFrontendToBackend), err)
+}
+
+// orderID returns the {id} path value, rejecting anything but a positive
+// integer before it is used to build the backend URL
+func orderID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id <= 0 {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid order id"))
+		return "", false
+	}
+	return strconv.Itoa(id), true
+}
+
+func readOrderBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBodyBytes))
+	if err != nil {
+		api.WriteProblem(w, r, api.NewProblem(api.ProblemInvalidRequest, http.StatusBadRequest, "Invalid request body: "+err.Error()))
+		return nil, false
+	}
+	return body, true
+}
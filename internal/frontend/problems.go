@@ -0,0 +1,77 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// wrapUpstream turns a backend error response into the frontend's own
+// problem, keeping the backend's problem as the cause. Client errors keep
+// their status and type: the request itself was at fault. Server errors
+// become 502, except 503 and 504 which callers may retry or attribute to a
+// hop. Responses that are not problems never came from the backend
+// application, e.g. an Envoy RBAC denial, so the hop itself failed.
+func wrapUpstream(apiErr *api.Error) *api.Problem {
+	cause := apiErr.Problem
+	if cause == nil {
+		return api.NewProblem(api.ProblemBackendError, http.StatusBadGateway,
+			fmt.Sprintf("Backend returned %d: %s", apiErr.StatusCode, apiErr.Message)).WithHop(HopFrontendToBackend)
+	}
+
+	if apiErr.StatusCode < http.StatusInternalServerError {
+		return &api.Problem{
+			Type:   cause.Type,
+			Title:  cause.Title,
+			Status: apiErr.StatusCode,
+			Detail: cause.Detail,
+			// A rate-limited caller waits as long as the backend asked
+			RetryAfter: cause.RetryAfter,
+			Cause:      cause,
+		}
+	}
+
+	hop := apiErr.Hop
+	if hop == "" {
+		hop = HopFrontendToBackend
+	}
+
+	var problem *api.Problem
+	switch apiErr.StatusCode {
+	case http.StatusGatewayTimeout:
+		problem = api.NewProblem(api.ProblemDeadlineExceeded, http.StatusGatewayTimeout, cause.Detail)
+	case http.StatusServiceUnavailable:
+		problem = api.NewProblem(api.ProblemBackendError, http.StatusServiceUnavailable, cause.Detail)
+	default:
+		problem = api.NewProblem(api.ProblemBackendError, http.StatusBadGateway, cause.Detail)
+	}
+	problem.Hop = hop
+	problem.Cause = cause
+	return problem
+}
+
+// writeProblem responds with p. err is the underlying error, if any, and is
+// logged for deadline problems.
+func (h *Handler) writeProblem(w http.ResponseWriter, r *http.Request, p *api.Problem, err error) {
+	if p.Status == http.StatusGatewayTimeout {
+		reason := p.Detail
+		if err != nil {
+			reason = err.Error()
+		}
+		h.logger.ErrorContext(r.Context(), "Deadline exceeded",
+			"event", EventDeadlineExceeded,
+			"hop", p.Hop,
+			"error", reason,
+		)
+	}
+	api.WriteProblem(w, r, p)
+}
+
+// problemSummary is a one-line description of p for the dashboard
+func problemSummary(p *api.Problem) string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
@@ -0,0 +1,41 @@
+package frontend
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/example/spire-workload-demo/internal/ratelimit"
+)
+
+// defaultRateLimits apply when RATE_LIMIT_FILE is unset. Each demo run reads
+// every order through the backend, so the demo routes are limited per
+// client; load tests run in process and are not affected.
+var defaultRateLimits = ratelimit.Config{
+	Routes: map[string]ratelimit.Limit{
+		"/api/demo":          {Rate: 5, Burst: 10},
+		"POST /demo/run":     {Rate: 5, Burst: 10},
+		"POST /api/loadtest": {Rate: 0.2, Burst: 2},
+	},
+}
+
+// newRateLimits limits each client IP per route
+func newRateLimits(logger *Logger) *ratelimit.Service {
+	return ratelimit.NewService("frontend", "client_ip", ratelimit.RemoteIP, defaultRateLimits, logger)
+}
+
+// StartRateLimiter loads the per-route limits from RATE_LIMIT_FILE, or the
+// defaults when it is unset, and reloads the file every
+// RATE_LIMIT_RELOAD_INTERVAL until ctx is cancelled. Call it before serving.
+func (h *Handler) StartRateLimiter(ctx context.Context) error {
+	return h.rateLimits.Start(ctx,
+		getEnv("RATE_LIMIT_FILE", ""),
+		getEnvAsDuration("RATE_LIMIT_RELOAD_INTERVAL", 10*time.Second),
+	)
+}
+
+// RateLimitMiddleware limits each client IP per route. Routes are the mux
+// patterns, as in the limits file.
+func (h *Handler) RateLimitMiddleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return h.rateLimits.Middleware(mux, next)
+}
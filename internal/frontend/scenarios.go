@@ -0,0 +1,277 @@
+package frontend
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/reqctx"
+	"github.com/example/spire-workload-demo/internal/scenarios"
+)
+
+// maxScenarioBodyBytes bounds how much of a rejection body is reported
+const maxScenarioBodyBytes = 512
+
+// scenario is a negative-path demo run on the frontend-to-backend hop. A
+// secondary client presents <name>.pem and <name>-key.pem from the scenario
+// certificate directory instead of the frontend's SVID.
+type scenario struct {
+	name        string
+	description string
+	expected    string
+	// Status the backend's Envoy should answer with; zero means it should
+	// reject the TLS handshake
+	expectStatus int
+	// The certificate is meant to have expired; any other scenario is
+	// unavailable once its certificate has
+	expired bool
+}
+
+// frontendScenarios are run by the frontend itself. Database scenarios are
+// forwarded to the backend, which owns the database clients.
+var frontendScenarios = map[string]scenario{
+	api.ScenarioImpostorIdentity: {
+		name:         api.ScenarioImpostorIdentity,
+		description:  "A valid SVID from the trust domain for a SPIFFE ID that Envoy RBAC on the backend does not allow",
+		expected:     "Envoy RBAC answers 403",
+		expectStatus: http.StatusForbidden,
+	},
+	api.ScenarioExpiredCert: {
+		name:        api.ScenarioExpiredCert,
+		description: "An SVID from the trust domain whose validity period has ended",
+		expected:    "Backend Envoy rejects the TLS handshake",
+		expired:     true,
+	},
+	api.ScenarioWrongTrustDomain: {
+		name:        api.ScenarioWrongTrustDomain,
+		description: "An SVID for the frontend's path signed by a CA outside the example.org trust domain",
+		expected:    "Backend Envoy rejects the TLS handshake",
+	},
+}
+
+// knownScenario reports whether name can be passed to runDemo
+func knownScenario(name string) bool {
+	_, ok := frontendScenarios[name]
+	return ok || name == api.ScenarioUnauthorizedDBClient
+}
+
+// scenarioClients builds the secondary mTLS clients. They connect to the
+// backend's Envoy directly; the frontend's own Envoy would present the
+// frontend's SVID instead.
+type scenarioClients struct {
+	// Backend Envoy inbound listener
+	backendURL string
+	// Scenario certificates and bundle.pem, the trust bundle used to verify
+	// the backend
+	certDir         string
+	backendSPIFFEID string
+}
+
+// newScenarioClients creates the scenario client settings from environment variables
+func newScenarioClients() *scenarioClients {
+	return &scenarioClients{
+		backendURL:      strings.TrimSuffix(getEnv("SCENARIO_BACKEND_URL", "https://backend.demo.svc.cluster.local:8080"), "/"),
+		certDir:         getEnv("SCENARIO_CERT_DIR", "/run/scenario-certs"),
+		backendSPIFFEID: getEnv("BACKEND_SPIFFE_ID", "spiffe://example.org/ns/demo/sa/backend"),
+	}
+}
+
+// client returns an HTTP client presenting the scenario's certificate and
+// the SPIFFE ID in it. Certificates are read on every run, so rotating the
+// secret needs no restart.
+func (c *scenarioClients) client(sc scenario) (*http.Client, string, error) {
+	cert, err := tls.LoadX509KeyPair(
+		filepath.Join(c.certDir, sc.name+".pem"),
+		filepath.Join(c.certDir, sc.name+"-key.pem"),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load scenario certificate: %w", err)
+	}
+	if !sc.expired {
+		if err := scenarios.CheckNotExpired(cert.Leaf); err != nil {
+			return nil, "", err
+		}
+	}
+
+	presented := ""
+	for _, uri := range cert.Leaf.URIs {
+		if uri.Scheme == "spiffe" {
+			presented = uri.String()
+			break
+		}
+	}
+
+	bundlePath := filepath.Join(c.certDir, "bundle.pem")
+	bundle, err := os.ReadFile(bundlePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read trust bundle: %w", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(bundle) {
+		return nil, "", fmt.Errorf("trust bundle %s contains no certificates", bundlePath)
+	}
+
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 2 * time.Second,
+		}).DialContext,
+		TLSClientConfig: &tls.Config{
+			// Present the certificate even when its issuer is not among the
+			// CAs the server asks for; the server must be the one to reject it
+			GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return &cert, nil
+			},
+			MinVersion: tls.VersionTLS12,
+			// SVIDs carry SPIFFE IDs, not DNS names; the backend is checked
+			// in VerifyPeerCertificate instead
+			InsecureSkipVerify: true,
+			VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+				return verifyBackendPeer(rawCerts, roots, c.backendSPIFFEID)
+			},
+		},
+		// Each run is a fresh handshake with the scenario identity
+		DisableKeepAlives: true,
+	}
+	return &http.Client{Transport: &reqctx.Transport{Base: transport}}, presented, nil
+}
+
+// probeScenario calls the backend's demo endpoint with the scenario's
+// identity and reports whether it was rejected as expected. A rejection is
+// the expected outcome, so it is returned as a result, not an error.
+func (h *Handler) probeScenario(ctx context.Context, sc scenario) (*DemoResult, error) {
+	ctx, cancel := context.WithTimeoutCause(ctx, h.backendTimeout, errBackendBudgetExhausted)
+	defer cancel()
+
+	client, presented, err := h.scenarios.client(sc)
+	if err != nil {
+		return nil, &demoError{
+			problem: api.NewProblem(api.ProblemScenarioUnavailable, http.StatusServiceUnavailable, err.Error()),
+			err:     err,
+		}
+	}
+
+	target := h.scenarios.backendURL + "/api/demo"
+	h.logger.LogConnectionAttempt(ctx, PatternEnvoySDS, target, presented)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "frontend-scenario-client")
+	setRequestBudget(ctx, req)
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	latencyMs := float64(time.Since(start).Microseconds()) / 1000
+
+	report := &api.ScenarioReport{
+		Name:        sc.name,
+		Description: sc.description,
+		Hop:         HopFrontendToBackend,
+		SPIFFEID:    presented,
+		Expected:    sc.expected,
+	}
+	status := ConnectionStatus{
+		Pattern:   PatternEnvoySDS,
+		LatencyMs: latencyMs,
+	}
+
+	switch {
+	case err != nil && errors.Is(context.Cause(ctx), errBackendBudgetExhausted):
+		return nil, &demoError{
+			problem: api.NewProblem(api.ProblemDeadlineExceeded, http.StatusGatewayTimeout,
+				"Deadline exceeded on "+HopFrontendToBackend).WithHop(HopFrontendToBackend),
+			err: fmt.Errorf("%w: %v", errBackendBudgetExhausted, err),
+		}
+
+	case err != nil:
+		h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, target, presented, err)
+		rejected := scenarios.IsRemoteTLSRejection(err)
+		status.Message = "Connection failed: " + err.Error()
+		report.Observed = "Connection failed before the backend answered: " + err.Error()
+		if rejected {
+			status.Message = "Backend Envoy rejected the TLS handshake: " + err.Error()
+			report.Observed = "TLS handshake rejected: " + err.Error()
+		}
+		report.Passed = rejected && sc.expectStatus == 0
+
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxScenarioBodyBytes))
+		resp.Body.Close()
+		observed := fmt.Sprintf("Backend answered %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode >= http.StatusBadRequest {
+			h.logger.LogConnectionFailure(ctx, PatternEnvoySDS, target, presented,
+				fmt.Errorf("backend answered %d", resp.StatusCode))
+		} else {
+			h.logger.LogConnectionSuccess(ctx, PatternEnvoySDS, target, presented, h.scenarios.backendSPIFFEID)
+		}
+		status.Success = resp.StatusCode < http.StatusBadRequest
+		status.Message = observed
+		report.Observed = observed
+		report.Passed = sc.expectStatus != 0 && resp.StatusCode == sc.expectStatus
+	}
+
+	scenarios.Log(ctx, h.logger, report)
+
+	return &DemoResult{
+		FrontendToBackend: status,
+		BackendToDatabase: ConnectionStatus{
+			Message: "Not attempted (scenario stops at frontend-to-backend)",
+			Pattern: PatternSpiffeHelper,
+		},
+		Timestamp: start,
+		Scenario:  report,
+	}, nil
+}
+
+// verifyBackendPeer validates the backend's chain against roots and checks
+// that the leaf certificate carries the expected SPIFFE ID
+func verifyBackendPeer(rawCerts [][]byte, roots *x509.CertPool, expectedID string) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server presented no certificate")
+	}
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := certs[0]
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		return fmt.Errorf("server certificate chain not trusted: %w", err)
+	}
+
+	var presented []string
+	for _, uri := range leaf.URIs {
+		presented = append(presented, uri.String())
+	}
+	if !slices.Contains(presented, expectedID) {
+		return fmt.Errorf("server SPIFFE ID mismatch: want %s, got %v", expectedID, presented)
+	}
+	return nil
+}
@@ -1,32 +1,41 @@
 // SPIRE/SPIFFE Demo UI Logic
 
 document.addEventListener('DOMContentLoaded', function() {
+    const demoForm = document.getElementById('demoForm');
     const runDemoBtn = document.getElementById('runDemoBtn');
     const loading = document.getElementById('loading');
     const ordersSection = document.getElementById('ordersSection');
     const ordersContainer = document.getElementById('ordersContainer');
 
-    // Status elements for Pattern 1 (Frontend to Backend)
-    const fe2beStatus = document.getElementById('fe2beStatus');
-    const fe2beMessage = document.getElementById('fe2beMessage');
+    // Workload topology diagram and edge table, from /api/topology
+    const topologyDiagram = document.getElementById('topologyDiagram');
+    const topologyEdges = document.getElementById('topologyEdges');
+    const topologyMessage = document.getElementById('topologyMessage');
 
-    // Status elements for Pattern 2 (Backend to Database)
-    const be2dbStatus = document.getElementById('be2dbStatus');
-    const be2dbMessage = document.getElementById('be2dbMessage');
+    // Problem details of the last failed demo run
+    const demoProblem = document.getElementById('demoProblem');
+
+    // Negative-path scenario selection and its expected/observed report
+    const scenarioSelect = document.getElementById('scenarioSelect');
+    const scenarioReport = document.getElementById('scenarioReport');
+
+    // Without JavaScript the form posts to /demo/run; here the demo runs in place
+    demoForm.addEventListener('submit', async function(event) {
+        event.preventDefault();
 
-    runDemoBtn.addEventListener('click', async function() {
         // Disable button and show loading
         runDemoBtn.disabled = true;
         loading.classList.remove('hidden');
         ordersSection.classList.add('hidden');
 
-        // Reset status indicators
-        resetStatus(fe2beStatus, fe2beMessage);
-        resetStatus(be2dbStatus, be2dbMessage);
+        clearProblem(demoProblem);
+        scenarioReport.innerHTML = '';
 
         try {
             // Call the backend demo endpoint
-            const response = await fetch('/api/demo', {
+            const scenario = scenarioSelect.value;
+            const path = scenario ? `/api/demo?scenario=${encodeURIComponent(scenario)}` : '/api/demo';
+            const response = await fetch(path, {
                 method: 'GET',
                 headers: {
                     'Content-Type': 'application/json'
@@ -34,24 +43,14 @@ document.addEventListener('DOMContentLoaded', function() {
             });
 
             if (!response.ok) {
-                throw new Error(`HTTP ${response.status}: ${response.statusText}`);
+                throw await responseError(response);
             }
 
             const result = await response.json();
 
-            // Update Pattern 1 status (Frontend to Backend via Envoy SDS)
-            updateConnectionStatus(
-                fe2beStatus,
-                fe2beMessage,
-                result.frontend_to_backend
-            );
-
-            // Update Pattern 2 status (Backend to Database via spiffe-helper)
-            updateConnectionStatus(
-                be2dbStatus,
-                be2dbMessage,
-                result.backend_to_database
-            );
+            if (result.scenario) {
+                scenarioReport.appendChild(renderScenarioReport(result.scenario));
+            }
 
             // Display orders if successful
             if (result.orders && result.orders.length > 0) {
@@ -61,79 +60,459 @@ document.addEventListener('DOMContentLoaded', function() {
 
         } catch (error) {
             console.error('Demo failed:', error);
-
-            // Show error for Pattern 1 (frontend-to-backend connection failed)
-            updateConnectionStatus(
-                fe2beStatus,
-                fe2beMessage,
-                {
-                    success: false,
-                    message: `Connection failed: ${error.message}`,
-                    pattern: 'envoy-sds'
-                }
-            );
-
-            // Pattern 2 is unknown if we couldn't reach backend
-            updateConnectionStatus(
-                be2dbStatus,
-                be2dbMessage,
-                {
-                    success: false,
-                    message: 'Unable to determine (frontend-to-backend failed)',
-                    pattern: 'spiffe-helper'
-                }
-            );
+            showProblem(demoProblem, error.problem);
         } finally {
             // Re-enable button and hide loading
             runDemoBtn.disabled = false;
             loading.classList.add('hidden');
+            // The run's connections were logged on both services
+            loadTopology();
         }
     });
 
-    function resetStatus(statusElement, messageElement) {
-        statusElement.className = 'status-indicator';
-        statusElement.innerHTML = `
-            <span class="status-icon">⏳</span>
-            <span class="status-text">Checking...</span>
-        `;
-        messageElement.textContent = '';
+    // responseError turns a failed response into an Error. Problem details
+    // (RFC 7807) are kept on error.problem.
+    async function responseError(response) {
+        const contentType = response.headers.get('Content-Type') || '';
+        if (contentType.includes('application/problem+json')) {
+            try {
+                const problem = await response.json();
+                const error = new Error(problem.detail ? `${problem.title}: ${problem.detail}` : problem.title);
+                error.problem = problem;
+                return error;
+            } catch (e) {
+                // Fall through to the status line
+            }
+        }
+        const text = await response.text().catch(() => '');
+        return new Error(`HTTP ${response.status}: ${text.trim() || response.statusText}`);
+    }
+
+    function clearProblem(container) {
+        container.innerHTML = '';
+        container.classList.add('hidden');
+    }
+
+    // Problem fields come from the services and are rendered as text
+    function showProblem(container, problem) {
+        clearProblem(container);
+        if (!problem) {
+            return;
+        }
+        container.appendChild(renderProblem(problem));
+        container.classList.remove('hidden');
     }
 
-    function updateConnectionStatus(statusElement, messageElement, connectionStatus) {
-        if (connectionStatus.success) {
-            statusElement.className = 'status-indicator status-success';
-            statusElement.innerHTML = `
-                <span class="status-icon"></span>
-                <span class="status-text">SUCCESS</span>
-            `;
-        } else {
-            statusElement.className = 'status-indicator status-error';
-            statusElement.innerHTML = `
-                <span class="status-icon"></span>
-                <span class="status-text">FAILED</span>
-            `;
+    function renderProblem(problem) {
+        const panel = document.createElement('div');
+        panel.className = 'problem';
+
+        const title = document.createElement('h4');
+        title.textContent = `${problem.status} ${problem.title}`;
+        panel.appendChild(title);
+
+        if (problem.detail) {
+            const detail = document.createElement('p');
+            detail.textContent = problem.detail;
+            panel.appendChild(detail);
         }
 
-        messageElement.textContent = connectionStatus.message || '';
+        const fields = [['Hop', problem.hop], ['Correlation ID', problem.correlation_id], ['Type', problem.type]];
+        fields.forEach(([label, value]) => {
+            if (!value) {
+                return;
+            }
+            const line = document.createElement('p');
+            line.className = 'problem-field';
+            const strong = document.createElement('strong');
+            strong.textContent = `${label}: `;
+            line.append(strong, value);
+            panel.appendChild(line);
+        });
+
+        if (problem.cause) {
+            const cause = document.createElement('div');
+            cause.className = 'problem-cause';
+            const label = document.createElement('p');
+            label.textContent = 'Caused by';
+            cause.append(label, renderProblem(problem.cause));
+            panel.appendChild(cause);
+        }
+        return panel;
+    }
+
+    // Scenario fields include certificate and error text; rendered as text
+    function renderScenarioReport(report) {
+        const panel = document.createElement('div');
+        panel.className = `scenario-report ${report.passed ? 'scenario-passed' : 'scenario-failed'}`;
+
+        const title = document.createElement('h4');
+        title.textContent = `Scenario ${report.name}: ${report.passed ? 'rejected as expected' : 'unexpected outcome'}`;
+
+        const description = document.createElement('p');
+        description.textContent = report.description;
+        panel.append(title, description);
+
+        const fields = [
+            ['Presented', `${report.spiffe_id || 'no SPIFFE ID'} on ${report.hop}`],
+            ['Expected', report.expected],
+            ['Observed', report.observed]
+        ];
+        fields.forEach(([label, value]) => {
+            const line = document.createElement('p');
+            const strong = document.createElement('strong');
+            strong.textContent = `${label}: `;
+            line.append(strong, value);
+            panel.appendChild(line);
+        });
+        return panel;
+    }
+
+    // Topology: nodes are placed in columns by their distance from a
+    // workload nothing connects to, so the demo path reads left to right
+    const svgNS = 'http://www.w3.org/2000/svg';
+    const nodeWidth = 200;
+    const nodeHeight = 60;
+    const columnWidth = 280;
+    const rowHeight = 100;
+    const margin = 20;
+
+    async function loadTopology() {
+        try {
+            const response = await fetch('/api/topology');
+            if (!response.ok) {
+                throw await responseError(response);
+            }
+            renderTopology(await response.json());
+        } catch (error) {
+            topologyMessage.textContent = `Failed to load topology: ${error.message}`;
+        }
+    }
+
+    function renderTopology(graph) {
+        const messages = [];
+        if (graph.edges.length === 0) {
+            messages.push('No connections observed yet. Run the demo to populate the graph.');
+        }
+        if (graph.backend_error) {
+            messages.push(`Backend observations unavailable: ${graph.backend_error}`);
+        }
+        if (graph.observers && graph.observers.length > 0) {
+            // Each backend replica records its own connections; a refresh
+            // may be answered by a different one
+            messages.push(`Observed by ${graph.observers.join(', ')}.`);
+        }
+        if (graph.truncated) {
+            messages.push('Some connections were not recorded: a pod reached its limit of tracked connections.');
+        }
+        topologyMessage.textContent = messages.join(' ');
+
+        renderTopologyEdges(graph.edges);
+        renderTopologyDiagram(graph);
+    }
+
+    function layoutTopology(graph) {
+        const incoming = new Set(graph.edges.map(edge => edge.to));
+        const column = new Map();
+        const queue = graph.nodes.filter(node => !incoming.has(node.id)).map(node => node.id);
+        queue.forEach(id => column.set(id, 0));
+        for (let i = 0; i < queue.length; i++) {
+            const id = queue[i];
+            graph.edges.filter(edge => edge.from === id && !column.has(edge.to)).forEach(edge => {
+                column.set(edge.to, column.get(id) + 1);
+                queue.push(edge.to);
+            });
+        }
+
+        // Nodes only reachable through a cycle start a column of their own
+        const positions = new Map();
+        const rows = [];
+        graph.nodes.forEach(node => {
+            const col = column.has(node.id) ? column.get(node.id) : 0;
+            rows[col] = (rows[col] || 0) + 1;
+            positions.set(node.id, {
+                x: margin + col * columnWidth,
+                y: margin + (rows[col] - 1) * rowHeight
+            });
+        });
+        return {
+            positions: positions,
+            width: margin * 2 + Math.max(rows.length - 1, 0) * columnWidth + nodeWidth,
+            height: margin * 2 + Math.max(...rows.filter(Boolean), 1) * rowHeight - (rowHeight - nodeHeight)
+        };
+    }
+
+    function svgElement(name, attributes) {
+        const element = document.createElementNS(svgNS, name);
+        Object.entries(attributes || {}).forEach(([key, value]) => element.setAttribute(key, value));
+        return element;
+    }
+
+    function svgTitle(text) {
+        const title = svgElement('title');
+        title.textContent = text;
+        return title;
+    }
+
+    // An edge is shown as failing when its latest outcome was a failure
+    function edgeSucceeded(edge) {
+        if (!edge.last_success) {
+            return false;
+        }
+        return !edge.last_failure || new Date(edge.last_success) >= new Date(edge.last_failure);
+    }
+
+    function renderTopologyDiagram(graph) {
+        topologyDiagram.innerHTML = '';
+        if (graph.nodes.length === 0) {
+            topologyDiagram.classList.add('hidden');
+            return;
+        }
+
+        const layout = layoutTopology(graph);
+        topologyDiagram.setAttribute('viewBox', `0 0 ${layout.width} ${layout.height}`);
+        topologyDiagram.style.maxWidth = `${layout.width}px`;
+
+        const defs = svgElement('defs');
+        [['arrowSuccess', '#10b981'], ['arrowFailure', '#ef4444']].forEach(([id, color]) => {
+            const marker = svgElement('marker', {
+                id: id, viewBox: '0 0 10 10', refX: 10, refY: 5,
+                markerWidth: 8, markerHeight: 8, orient: 'auto'
+            });
+            marker.appendChild(svgElement('path', { d: 'M 0 0 L 10 5 L 0 10 z', fill: color }));
+            defs.appendChild(marker);
+        });
+        topologyDiagram.appendChild(defs);
+
+        // Several patterns between one pair of workloads stack their labels
+        const pairCount = new Map();
+        graph.edges.forEach(edge => {
+            const from = layout.positions.get(edge.from);
+            const to = layout.positions.get(edge.to);
+            const pair = `${edge.from} ${edge.to}`;
+            const index = pairCount.get(pair) || 0;
+            pairCount.set(pair, index + 1);
+
+            const x1 = from.x + nodeWidth;
+            const y1 = from.y + nodeHeight / 2;
+            const x2 = to.x;
+            const y2 = to.y + nodeHeight / 2;
+            const bend = Math.max(Math.abs(x2 - x1) / 2, 40);
+
+            const succeeded = edgeSucceeded(edge);
+            const group = svgElement('g', { class: `topology-edge ${succeeded ? 'edge-success' : 'edge-failure'}` });
+            group.appendChild(svgElement('path', {
+                d: `M ${x1} ${y1} C ${x1 + bend} ${y1}, ${x2 - bend} ${y2}, ${x2} ${y2}`,
+                'marker-end': `url(#${succeeded ? 'arrowSuccess' : 'arrowFailure'})`
+            }));
+
+            const label = svgElement('text', {
+                x: (x1 + x2) / 2, y: (y1 + y2) / 2 - 6 + index * 14, 'text-anchor': 'middle'
+            });
+            label.textContent = `${edge.pattern} ✓${edge.success_count} ✗${edge.failure_count}`;
+            group.appendChild(label);
+
+            const details = [`${edge.from} → ${edge.to}`];
+            if (edge.last_success) {
+                details.push(`Last success: ${new Date(edge.last_success).toLocaleTimeString()}`);
+            }
+            if (edge.last_failure) {
+                details.push(`Last failure: ${new Date(edge.last_failure).toLocaleTimeString()} ${edge.last_error || ''}`);
+            }
+            group.appendChild(svgTitle(details.join('\n')));
+            topologyDiagram.appendChild(group);
+        });
+
+        graph.nodes.forEach(node => {
+            const position = layout.positions.get(node.id);
+            const group = svgElement('g', {
+                class: `topology-node${node.spiffe_id ? '' : ' unidentified'}`,
+                transform: `translate(${position.x}, ${position.y})`
+            });
+            group.appendChild(svgElement('rect', { width: nodeWidth, height: nodeHeight, rx: 8 }));
+
+            const name = svgElement('text', { class: 'node-name', x: 10, y: 24 });
+            name.textContent = node.workload;
+            const detail = svgElement('text', { class: 'node-detail', x: 10, y: 44 });
+            detail.textContent = node.patterns.join(', ') || 'no pattern';
+            group.append(name, detail, svgTitle(node.spiffe_id || `${node.id} (no SPIFFE ID presented)`));
+            topologyDiagram.appendChild(group);
+        });
+
+        topologyDiagram.classList.remove('hidden');
+    }
+
+    // Edge fields include SPIFFE IDs and error text; rendered as text
+    function renderTopologyEdges(edges) {
+        topologyEdges.innerHTML = '';
+        edges.forEach(edge => {
+            const row = document.createElement('tr');
+            const cells = [
+                codeCell(edge.from),
+                codeCell(edge.to),
+                badgeCell(edge.pattern),
+                edge.success_count,
+                edge.failure_count,
+                edge.last_success ? new Date(edge.last_success).toLocaleTimeString() : '-',
+                edge.last_failure
+                    ? `${new Date(edge.last_failure).toLocaleTimeString()}${edge.last_error ? `: ${edge.last_error}` : ''}`
+                    : '-'
+            ];
+            cells.forEach(value => {
+                const cell = document.createElement('td');
+                cell.append(value);
+                row.appendChild(cell);
+            });
+            topologyEdges.appendChild(row);
+        });
+    }
+
+    function codeCell(text) {
+        const code = document.createElement('code');
+        code.textContent = text;
+        return code;
+    }
+
+    function badgeCell(text) {
+        const badge = document.createElement('span');
+        badge.className = 'pattern-badge';
+        badge.textContent = text;
+        return badge;
     }
 
     function displayOrders(orders) {
         ordersContainer.innerHTML = '';
 
         orders.forEach(order => {
-            const orderCard = document.createElement('div');
-            orderCard.className = 'order-card';
+            ordersContainer.appendChild(renderOrderCard(order, false));
+        });
+    }
+
+    // Order cards are built with textContent: descriptions are user input
+    function renderOrderCard(order, editable) {
+        const orderCard = document.createElement('div');
+        orderCard.className = 'order-card';
+
+        const title = document.createElement('h4');
+        title.textContent = `Order #${order.id}`;
+
+        const description = document.createElement('p');
+        description.textContent = order.description;
+
+        const status = document.createElement('p');
+        status.innerHTML = '<strong>Status:</strong> ';
+        const badge = document.createElement('span');
+        badge.className = `order-status ${order.status}`;
+        badge.textContent = order.status;
+        status.appendChild(badge);
+
+        const created = document.createElement('p');
+        created.innerHTML = '<strong>Created:</strong> ';
+        created.append(new Date(order.created_at).toLocaleDateString());
 
-            const createdDate = new Date(order.created_at).toLocaleDateString();
+        orderCard.append(title, description, status, created);
 
-            orderCard.innerHTML = `
-                <h4>Order #${order.id}</h4>
-                <p>${order.description}</p>
-                <p><strong>Status:</strong> <span class="order-status ${order.status}">${order.status}</span></p>
-                <p><strong>Created:</strong> ${createdDate}</p>
-            `;
+        if (editable) {
+            orderCard.appendChild(renderStatusSelect(order));
+        }
+        return orderCard;
+    }
+
+    // Order browser
+    const orderList = document.getElementById('orderList');
+    const refreshOrdersBtn = document.getElementById('refreshOrdersBtn');
+    const ordersCacheStatus = document.getElementById('ordersCacheStatus');
+    const createOrderForm = document.getElementById('createOrderForm');
+    const newOrderDescription = document.getElementById('newOrderDescription');
+    const orderBrowserMessage = document.getElementById('orderBrowserMessage');
+    const orderProblem = document.getElementById('orderProblem');
+
+    const orderStatuses = ['pending', 'processing', 'completed', 'failed'];
+
+    async function orderRequest(method, path, body) {
+        const response = await fetch(path, {
+            method: method,
+            headers: { 'Content-Type': 'application/json' },
+            body: body === undefined ? undefined : JSON.stringify(body)
+        });
+        if (!response.ok) {
+            const error = await responseError(response);
+            showProblem(orderProblem, error.problem);
+            throw error;
+        }
+        clearProblem(orderProblem);
+        return response;
+    }
+
+    async function loadOrders(readYourWrites) {
+        refreshOrdersBtn.disabled = true;
+        orderBrowserMessage.textContent = '';
+        try {
+            const path = readYourWrites ? '/api/orders?read_your_writes=true' : '/api/orders';
+            const response = await orderRequest('GET', path);
+            const orders = await response.json() || [];
+
+            const cache = response.headers.get('X-Cache');
+            ordersCacheStatus.textContent = cache ? `cache: ${cache.toLowerCase()}` : 'cache: bypassed';
+
+            orderList.innerHTML = '';
+            orders.forEach(order => {
+                orderList.appendChild(renderOrderCard(order, true));
+            });
+            if (orders.length === 0) {
+                orderBrowserMessage.textContent = 'No orders visible to this workload.';
+            }
+        } catch (error) {
+            orderBrowserMessage.textContent = `Failed to load orders: ${error.message}`;
+        } finally {
+            refreshOrdersBtn.disabled = false;
+        }
+    }
+
+    function renderStatusSelect(order) {
+        const select = document.createElement('select');
+        // Seed data may carry statuses outside the worker's state machine
+        const statuses = orderStatuses.includes(order.status) ? orderStatuses : [order.status, ...orderStatuses];
+        statuses.forEach(status => {
+            const option = document.createElement('option');
+            option.value = status;
+            option.textContent = status;
+            option.selected = status === order.status;
+            select.appendChild(option);
+        });
 
-            ordersContainer.appendChild(orderCard);
+        select.addEventListener('change', async function() {
+            select.disabled = true;
+            try {
+                await orderRequest('PUT', `/api/orders/${order.id}`, { status: select.value });
+                await loadOrders(true);
+            } catch (error) {
+                orderBrowserMessage.textContent = `Failed to update order #${order.id}: ${error.message}`;
+                select.value = order.status;
+                select.disabled = false;
+            }
         });
+        return select;
     }
+
+    createOrderForm.addEventListener('submit', async function(event) {
+        event.preventDefault();
+        try {
+            await orderRequest('POST', '/api/orders', { description: newOrderDescription.value });
+            newOrderDescription.value = '';
+            await loadOrders(true);
+        } catch (error) {
+            orderBrowserMessage.textContent = `Failed to create order: ${error.message}`;
+        }
+    });
+
+    refreshOrdersBtn.addEventListener('click', function() {
+        loadOrders(false);
+    });
+
+    loadOrders(false);
+
+    // Other clients' traffic changes the graph too
+    loadTopology();
+    setInterval(loadTopology, 10000);
 });
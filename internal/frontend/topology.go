@@ -0,0 +1,38 @@
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/example/spire-workload-demo/api"
+	"github.com/example/spire-workload-demo/internal/topology"
+)
+
+// TopologyHandler handles GET /api/topology: the connections the frontend
+// has logged merged with those the backend has
+func (h *Handler) TopologyHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.topology(r.Context()))
+	}
+}
+
+// topology builds the workload graph. If the backend cannot be asked within
+// the health probe timeout, the frontend's half is returned with
+// backend_error set.
+func (h *Handler) topology(ctx context.Context) *api.Topology {
+	fetchCtx, cancel := context.WithTimeout(ctx, h.health.timeout)
+	defer cancel()
+
+	backendGraph, err := h.backend.Topology(fetchCtx)
+	graph := topology.Merge(h.logger.Topology(), backendGraph)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Failed to fetch backend topology",
+			"pattern", PatternEnvoySDS,
+			"error", err.Error(),
+		)
+		graph.BackendError = err.Error()
+	}
+	return graph
+}
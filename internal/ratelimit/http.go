@@ -0,0 +1,165 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// Event names logged by Service, the same in both services' logs
+const (
+	EventLimited = "rate_limited"
+	EventReload  = "rate_limit_reload"
+)
+
+// Logger is the part of the services' loggers a Service writes to
+type Logger interface {
+	Info(message string, args ...any)
+	Error(message string, args ...any)
+	WarnContext(ctx context.Context, message string, args ...any)
+}
+
+// KeyFunc returns the key of the bucket a request is charged to
+type KeyFunc func(r *http.Request) string
+
+// Service applies per-route limits to one HTTP service: it loads and
+// reloads the limits file, rejects requests over their limit and reports
+// the decisions as metrics
+type Service struct {
+	// Prefix of the metric names, e.g. "backend"
+	name string
+	// Log attribute the key is written under, e.g. "client_ip"
+	keyAttr  string
+	key      KeyFunc
+	defaults Config
+	logger   Logger
+
+	// Set by Start before serving
+	limiter        *Limiter
+	reloads        atomic.Int64
+	reloadFailures atomic.Int64
+}
+
+// NewService creates a Service that charges requests to key(r) and uses
+// defaults when no limits file is configured
+func NewService(name, keyAttr string, key KeyFunc, defaults Config, logger Logger) *Service {
+	return &Service{name: name, keyAttr: keyAttr, key: key, defaults: defaults, logger: logger}
+}
+
+// Start loads the limits from path, or the defaults when path is empty, and
+// reloads the file every interval until ctx is cancelled. Call it before
+// serving.
+func (s *Service) Start(ctx context.Context, path string, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("RATE_LIMIT_RELOAD_INTERVAL must be positive, got %s", interval)
+	}
+
+	if path == "" {
+		s.limiter = New(s.defaults)
+		s.logLimits("Rate limits loaded", "built-in", s.defaults)
+		return nil
+	}
+
+	limiter, err := NewFromFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to load rate limits: %w", err)
+	}
+	s.limiter = limiter
+	s.logLimits("Rate limits loaded", path, limiter.Config())
+
+	go limiter.Watch(ctx, path, interval, func(config Config, err error) {
+		if err != nil {
+			s.reloadFailures.Add(1)
+			s.logger.Error("Rate limit reload failed; keeping the current limits",
+				"event", EventReload,
+				"file", path,
+				"error", err.Error(),
+			)
+			return
+		}
+		s.reloads.Add(1)
+		s.logLimits("Rate limits reloaded", path, config)
+	})
+	return nil
+}
+
+func (s *Service) logLimits(message, source string, config Config) {
+	s.logger.Info(message,
+		"event", EventReload,
+		"source", source,
+		"default_rate", config.Default.Rate,
+		"default_burst", config.Default.Burst,
+		"routes", config.Routes,
+	)
+}
+
+// Middleware limits each key per route. Routes are the mux patterns, as in
+// the limits file; requests the mux does not route pass through.
+func (s *Service) Middleware(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		if route == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := s.key(r)
+		decision := s.limiter.Allow(route, key)
+		if decision.Allowed {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if decision.Log {
+			s.logger.WarnContext(r.Context(), "Request rate limited",
+				"event", EventLimited,
+				"route", route,
+				s.keyAttr, key,
+				"rate", decision.Limit.Rate,
+				"burst", decision.Limit.Burst,
+				"retry_after_ms", decision.RetryAfter.Milliseconds(),
+				"suppressed", decision.Suppressed,
+			)
+		}
+		problem := api.NewProblem(api.ProblemRateLimited, http.StatusTooManyRequests,
+			fmt.Sprintf("Rate limit of %g requests per second exceeded for %s", decision.Limit.Rate, route))
+		problem.RetryAfter = decision.RetryAfterSeconds()
+		api.WriteProblem(w, r, problem)
+	})
+}
+
+// WriteMetrics writes the decision counts per route and the reload counts
+// in the Prometheus text format
+func (s *Service) WriteMetrics(w http.ResponseWriter) {
+	decisions := s.name + "_rate_limit_decisions_total"
+	fmt.Fprintf(w, "# HELP %s Rate limit decisions per route.\n# TYPE %s counter\n", decisions, decisions)
+	if s.limiter != nil {
+		for _, stats := range s.limiter.Stats() {
+			fmt.Fprintf(w, "%s{route=%q,decision=\"allowed\"} %d\n", decisions, stats.Route, stats.Allowed)
+			fmt.Fprintf(w, "%s{route=%q,decision=\"limited\"} %d\n", decisions, stats.Route, stats.Limited)
+		}
+	}
+	for _, metric := range []struct {
+		name, help string
+		value      int64
+	}{
+		{s.name + "_rate_limit_reloads_total", "Rate limit file changes applied.", s.reloads.Load()},
+		{s.name + "_rate_limit_reload_failures_total", "Rate limit file changes rejected as invalid.", s.reloadFailures.Load()},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", metric.name, metric.help, metric.name, metric.name, metric.value)
+	}
+}
+
+// RemoteIP returns the IP address of the connection's peer
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
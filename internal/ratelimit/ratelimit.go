@@ -0,0 +1,298 @@
+// Package ratelimit implements per-route token-bucket rate limiting shared by
+// the frontend and backend. Each service chooses the key a bucket belongs
+// to: the client IP on the frontend, the caller's SPIFFE ID on the backend.
+// Limits come from a JSON file that is reloaded while the service runs.
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Rate tokens per second, holding at most Burst.
+// A Rate of zero or less does not limit.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Config holds the limit for each route, keyed by the ServeMux pattern that
+// matched the request, e.g. "GET /api/orders/{id}". Routes not listed use
+// Default.
+type Config struct {
+	Default Limit            `json:"default"`
+	Routes  map[string]Limit `json:"routes"`
+}
+
+// limit returns the limit that applies to route
+func (c Config) limit(route string) Limit {
+	if limit, ok := c.Routes[route]; ok {
+		return limit
+	}
+	return c.Default
+}
+
+// Validate rejects limits that cannot be enforced
+func (c Config) Validate() error {
+	check := func(name string, limit Limit) error {
+		if math.IsNaN(limit.Rate) || math.IsInf(limit.Rate, 0) {
+			return fmt.Errorf("%s: rate must be a finite number", name)
+		}
+		if limit.Rate > 0 && limit.Burst < 1 {
+			return fmt.Errorf("%s: burst must be at least 1 when rate is set", name)
+		}
+		return nil
+	}
+
+	if err := check("default", c.Default); err != nil {
+		return err
+	}
+	for route, limit := range c.Routes {
+		if err := check(fmt.Sprintf("route %q", route), limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parse(data []byte) (Config, error) {
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid rate limit configuration: %w", err)
+	}
+	return config, nil
+}
+
+// Decision is the outcome of one request's admission
+type Decision struct {
+	Allowed bool
+	Limit   Limit
+	// Time until a token is available; set when the request was rejected
+	RetryAfter time.Duration
+	// Log is set on the first rejection of a bucket in a logInterval.
+	// Suppressed counts the rejections not logged since the previous one,
+	// so a client hammering a route cannot flood the logs.
+	Log        bool
+	Suppressed int64
+}
+
+// RetryAfterSeconds is RetryAfter rounded up to whole seconds for the
+// Retry-After header, which has no finer resolution
+func (d Decision) RetryAfterSeconds() int {
+	return max(int(math.Ceil(d.RetryAfter.Seconds())), 1)
+}
+
+// logInterval bounds how often rejections of one bucket are logged
+const logInterval = time.Second
+
+// idleAfter is how long a full bucket is kept; a new one behaves the same
+const idleAfter = 5 * time.Minute
+
+type bucketKey struct {
+	route, key string
+}
+
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	lastLog    time.Time
+	suppressed int64
+}
+
+// RouteStats counts the decisions made for one route
+type RouteStats struct {
+	Route   string
+	Allowed int64
+	Limited int64
+}
+
+type counts struct {
+	allowed, limited int64
+}
+
+// Limiter admits requests against the configured limits
+type Limiter struct {
+	mu        sync.Mutex
+	config    Config
+	buckets   map[bucketKey]*bucket
+	counts    map[string]*counts
+	lastSweep time.Time
+	// Contents of the file the configuration was loaded from, to skip
+	// unchanged reloads; only used by the goroutine running Watch
+	loaded []byte
+}
+
+// New creates a limiter enforcing config
+func New(config Config) *Limiter {
+	return &Limiter{
+		config:    config,
+		buckets:   make(map[bucketKey]*bucket),
+		counts:    make(map[string]*counts),
+		lastSweep: time.Now(),
+	}
+}
+
+// Config returns the limits in force
+func (l *Limiter) Config() Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config
+}
+
+// SetConfig replaces the limits. Every bucket starts full again under the
+// new limits.
+func (l *Limiter) SetConfig(config Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config = config
+	clear(l.buckets)
+}
+
+// Allow takes a token from the bucket for key on route, if one is available
+func (l *Limiter) Allow(route, key string) Decision {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.counts[route]
+	if count == nil {
+		count = &counts{}
+		l.counts[route] = count
+	}
+
+	limit := l.config.limit(route)
+	if limit.Rate <= 0 {
+		count.allowed++
+		return Decision{Allowed: true, Limit: limit}
+	}
+
+	l.sweep(now)
+
+	id := bucketKey{route: route, key: key}
+	b := l.buckets[id]
+	if b == nil {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		l.buckets[id] = b
+	}
+	b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		count.allowed++
+		return Decision{Allowed: true, Limit: limit}
+	}
+
+	count.limited++
+	decision := Decision{
+		Limit:      limit,
+		RetryAfter: time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)),
+	}
+	if now.Sub(b.lastLog) >= logInterval {
+		decision.Log = true
+		decision.Suppressed = b.suppressed
+		b.lastLog = now
+		b.suppressed = 0
+	} else {
+		b.suppressed++
+	}
+	return decision
+}
+
+// sweep drops buckets that have refilled and sat idle, so one-off clients
+// do not accumulate. l.mu must be held.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleAfter {
+		return
+	}
+	l.lastSweep = now
+	for id, b := range l.buckets {
+		if now.Sub(b.last) >= idleAfter {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+// Stats returns the decision counts per route, sorted by route
+func (l *Limiter) Stats() []RouteStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]RouteStats, 0, len(l.counts))
+	for route, count := range l.counts {
+		stats = append(stats, RouteStats{Route: route, Allowed: count.allowed, Limited: count.limited})
+	}
+	slices.SortFunc(stats, func(a, b RouteStats) int {
+		return strings.Compare(a.Route, b.Route)
+	})
+	return stats
+}
+
+// Watch reads path every interval until ctx is cancelled and applies the
+// configuration when the file's contents change, e.g. when Kubernetes
+// updates a mounted ConfigMap. onReload is called after each change with
+// the error, if any; an invalid file leaves the current limits in force.
+func (l *Limiter) Watch(ctx context.Context, path string, interval time.Duration, onReload func(Config, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(data, l.loaded) {
+			continue
+		}
+		var config Config
+		if err == nil {
+			config, err = parse(data)
+		}
+		if err != nil {
+			// Report a broken file once, not on every tick
+			if err.Error() != lastErr {
+				lastErr = err.Error()
+				onReload(Config{}, err)
+			}
+			continue
+		}
+
+		lastErr = ""
+		l.loaded = data
+		l.SetConfig(config)
+		onReload(config, nil)
+	}
+}
+
+// NewFromFile creates a limiter from a configuration file, ready to Watch it
+func NewFromFile(path string) (*Limiter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	l := New(config)
+	l.loaded = data
+	return l, nil
+}
@@ -0,0 +1,62 @@
+// Package scenarios holds the parts of the negative-path demo scenarios
+// (/api/demo?scenario=) shared by the frontend and backend: logging a
+// scenario's outcome and classifying how the peer refused its identity.
+package scenarios
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// EventResult is the event logged with every scenario outcome
+const EventResult = "scenario_result"
+
+// Logger is the part of the services' loggers a scenario outcome is
+// written to
+type Logger interface {
+	InfoContext(ctx context.Context, message string, args ...any)
+	ErrorContext(ctx context.Context, message string, args ...any)
+}
+
+// Log records a scenario's outcome. An identity that was not rejected is
+// logged as an error: the demo's security boundary did not hold.
+func Log(ctx context.Context, logger Logger, report *api.ScenarioReport) {
+	attrs := []any{
+		"event", EventResult,
+		"scenario", report.Name,
+		"hop", report.Hop,
+		"spiffe_id", report.SPIFFEID,
+		"expected", report.Expected,
+		"observed", report.Observed,
+		"passed", report.Passed,
+	}
+	if report.Passed {
+		logger.InfoContext(ctx, "Scenario identity rejected as expected", attrs...)
+		return
+	}
+	logger.ErrorContext(ctx, "Scenario identity was not rejected", attrs...)
+}
+
+// IsRemoteTLSRejection reports whether the peer aborted the connection with
+// a TLS alert, e.g. because it did not trust our certificate
+func IsRemoteTLSRejection(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "remote error"
+}
+
+// CheckNotExpired returns an error once a scenario certificate that should
+// be valid has expired. The peer would then reject it for its expiry rather
+// than its identity, so the scenario would pass without showing anything.
+func CheckNotExpired(cert *x509.Certificate) error {
+	if time.Now().After(cert.NotAfter) {
+		return fmt.Errorf("scenario certificate expired at %s; rerun scripts/06-create-scenario-certs.sh",
+			cert.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,361 @@
+// Package topology builds the graph of workloads and the connections between
+// them from the connection events the frontend and backend already log. Each
+// pod records its own events in process; the frontend merges the graph of
+// the backend pod that answered into its own for /api/topology, and the
+// graph names the pods it was observed by.
+package topology
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/spire-workload-demo/api"
+)
+
+// Event names and attribute keys written by the services' loggers
+const (
+	eventConnectionSuccess = "connection_success"
+	eventConnectionFailure = "connection_failure"
+
+	keyEvent   = "event"
+	keyPattern = "pattern"
+	keySPIFFE  = "spiffe_id"
+	keyPeer    = "peer_spiffe_id"
+	keyTarget  = "target"
+	keyError   = "error"
+)
+
+// edgeKey identifies an edge as recorded. to is the peer's SPIFFE ID, or
+// the target address when the peer was not identified.
+type edgeKey struct {
+	from, to, pattern string
+}
+
+type edgeStats struct {
+	successes   int64
+	failures    int64
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   string
+}
+
+// Bounds on what a Recorder keeps. Edge and peer keys come from SPIFFE IDs
+// and targets callers can influence, so connections to new keys past the
+// caps are not recorded; existing edges keep counting.
+const (
+	maxEdges = 256
+	maxPeers = 256
+)
+
+// Recorder accumulates connection outcomes observed by one pod
+type Recorder struct {
+	// observer names the pod, e.g. "backend/backend-6c9f-x2lq"
+	observer string
+
+	mu    sync.Mutex
+	edges map[edgeKey]*edgeStats
+	// Peer SPIFFE ID seen on successful connections to each target address.
+	// Failures carry no peer, so they are attributed through this map.
+	peers map[string]string
+	// truncated is set once an edge or peer was dropped at its cap
+	truncated bool
+}
+
+// NewRecorder creates an empty recorder for the named observer
+func NewRecorder(observer string) *Recorder {
+	return &Recorder{
+		observer: observer,
+		edges:    make(map[edgeKey]*edgeStats),
+		peers:    make(map[string]string),
+	}
+}
+
+// Observer returns "component/hostname", which is the pod name on
+// Kubernetes, to label what one pod's Recorder observed
+func Observer(component string) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return component
+	}
+	return component + "/" + host
+}
+
+// observe records a connection_success or connection_failure event.
+// Events with a target were logged by the client side of the connection:
+// spiffe_id is the caller. Events without a target were logged by the
+// server side after validating the caller, e.g. the backend behind Envoy
+// RBAC: peer_spiffe_id is the caller.
+func (r *Recorder) observe(at time.Time, attrs map[string]string) {
+	event := attrs[keyEvent]
+	if event != eventConnectionSuccess && event != eventConnectionFailure {
+		return
+	}
+
+	self, peer, target := attrs[keySPIFFE], attrs[keyPeer], attrs[keyTarget]
+	var key edgeKey
+	switch {
+	case target != "":
+		key = edgeKey{from: self, to: peer}
+		addr := targetAddress(target)
+		if peer == "" {
+			key.to = addr
+		}
+		r.mu.Lock()
+		if peer != "" {
+			if _, known := r.peers[addr]; known || len(r.peers) < maxPeers {
+				r.peers[addr] = peer
+			} else {
+				r.truncated = true
+			}
+		}
+		r.mu.Unlock()
+	case peer != "":
+		key = edgeKey{from: peer, to: self}
+	default:
+		return
+	}
+	if key.from == "" || key.to == "" {
+		return
+	}
+	key.pattern = attrs[keyPattern]
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.edges[key]
+	if stats == nil {
+		if len(r.edges) >= maxEdges {
+			r.truncated = true
+			return
+		}
+		stats = &edgeStats{}
+		r.edges[key] = stats
+	}
+	if event == eventConnectionSuccess {
+		stats.successes++
+		stats.lastSuccess = at
+		return
+	}
+	stats.failures++
+	stats.lastFailure = at
+	stats.lastError = attrs[keyError]
+}
+
+// Graph returns the connections recorded so far
+func (r *Recorder) Graph() *api.Topology {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Edges recorded against an address before its peer was known belong
+	// to the same edge as the later, identified ones
+	resolved := make(map[edgeKey]*edgeStats, len(r.edges))
+	for key, stats := range r.edges {
+		if peer, ok := r.peers[key.to]; ok {
+			key.to = peer
+		}
+		sum := resolved[key]
+		if sum == nil {
+			sum = &edgeStats{}
+			resolved[key] = sum
+		}
+		sum.successes += stats.successes
+		sum.failures += stats.failures
+		if stats.lastSuccess.After(sum.lastSuccess) {
+			sum.lastSuccess = stats.lastSuccess
+		}
+		if stats.lastFailure.After(sum.lastFailure) {
+			sum.lastFailure = stats.lastFailure
+			sum.lastError = stats.lastError
+		}
+	}
+
+	edges := make([]api.TopologyEdge, 0, len(resolved))
+	for key, stats := range resolved {
+		edges = append(edges, toEdge(key, stats))
+	}
+	graph := build(edges)
+	graph.Observers = []string{r.observer}
+	graph.Truncated = r.truncated
+	return graph
+}
+
+// Merge combines graphs observed by different services. Both ends of a
+// connection may have logged it, so counts are the larger of the two
+// observations rather than their sum.
+func Merge(graphs ...*api.Topology) *api.Topology {
+	merged := make(map[edgeKey]api.TopologyEdge)
+	observers := []string{}
+	truncated := false
+	for _, graph := range graphs {
+		if graph == nil {
+			continue
+		}
+		observers = append(observers, graph.Observers...)
+		truncated = truncated || graph.Truncated
+		for _, edge := range graph.Edges {
+			key := edgeKey{from: edge.From, to: edge.To, pattern: edge.Pattern}
+			current, ok := merged[key]
+			if !ok {
+				merged[key] = edge
+				continue
+			}
+			current.SuccessCount = max(current.SuccessCount, edge.SuccessCount)
+			current.FailureCount = max(current.FailureCount, edge.FailureCount)
+			current.LastSuccess = latest(current.LastSuccess, edge.LastSuccess)
+			if edge.LastFailure != nil && (current.LastFailure == nil || edge.LastFailure.After(*current.LastFailure)) {
+				current.LastFailure = edge.LastFailure
+				current.LastError = edge.LastError
+			}
+			merged[key] = current
+		}
+	}
+
+	edges := make([]api.TopologyEdge, 0, len(merged))
+	for _, edge := range merged {
+		edges = append(edges, edge)
+	}
+	graph := build(edges)
+	slices.Sort(observers)
+	graph.Observers = slices.Compact(observers)
+	graph.Truncated = truncated
+	return graph
+}
+
+// build derives the nodes from the edges and sorts both
+func build(edges []api.TopologyEdge) *api.Topology {
+	slices.SortFunc(edges, func(a, b api.TopologyEdge) int {
+		return strings.Compare(a.From+"\x00"+a.To+"\x00"+a.Pattern, b.From+"\x00"+b.To+"\x00"+b.Pattern)
+	})
+
+	nodes := make(map[string]*api.TopologyNode)
+	for _, edge := range edges {
+		for _, id := range []string{edge.From, edge.To} {
+			node := nodes[id]
+			if node == nil {
+				node = newNode(id)
+				nodes[id] = node
+			}
+			if edge.Pattern != "" && !slices.Contains(node.Patterns, edge.Pattern) {
+				node.Patterns = append(node.Patterns, edge.Pattern)
+			}
+		}
+	}
+
+	graph := &api.Topology{
+		Nodes:       make([]api.TopologyNode, 0, len(nodes)),
+		Edges:       edges,
+		Observers:   []string{},
+		GeneratedAt: time.Now(),
+	}
+	for _, node := range nodes {
+		slices.Sort(node.Patterns)
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	slices.SortFunc(graph.Nodes, func(a, b api.TopologyNode) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return graph
+}
+
+// newNode names a node after the service account in its SPIFFE ID
+func newNode(id string) *api.TopologyNode {
+	node := &api.TopologyNode{ID: id, Workload: id, Patterns: []string{}}
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "spiffe" {
+		return node
+	}
+
+	node.SPIFFEID = id
+	node.Workload = u.Host
+	if i := strings.LastIndex(u.Path, "/sa/"); i >= 0 {
+		node.Workload = u.Path[i+len("/sa/"):]
+	} else if path := strings.Trim(u.Path, "/"); path != "" {
+		node.Workload = path[strings.LastIndex(path, "/")+1:]
+	}
+	return node
+}
+
+func toEdge(key edgeKey, stats *edgeStats) api.TopologyEdge {
+	edge := api.TopologyEdge{
+		From:         key.from,
+		To:           key.to,
+		Pattern:      key.pattern,
+		SuccessCount: stats.successes,
+		FailureCount: stats.failures,
+		LastError:    stats.lastError,
+	}
+	if !stats.lastSuccess.IsZero() {
+		t := stats.lastSuccess
+		edge.LastSuccess = &t
+	}
+	if !stats.lastFailure.IsZero() {
+		t := stats.lastFailure
+		edge.LastFailure = &t
+	}
+	return edge
+}
+
+func latest(a, b *time.Time) *time.Time {
+	if a == nil || (b != nil && b.After(*a)) {
+		return b
+	}
+	return a
+}
+
+// targetAddress reduces a URL target to its host, so calls to different
+// paths on one service share an edge. Other targets, e.g. a database host
+// or file, are used as they are.
+func targetAddress(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return target
+}
+
+// LogHandler records connection events into a Recorder before passing every
+// record on to the wrapped handler
+type LogHandler struct {
+	slog.Handler
+	recorder *Recorder
+	// Attributes added with WithAttrs, which Handle does not see on the record
+	attrs []slog.Attr
+}
+
+// NewLogHandler wraps h, recording into recorder
+func NewLogHandler(h slog.Handler, recorder *Recorder) *LogHandler {
+	return &LogHandler{Handler: h, recorder: recorder}
+}
+
+// Handle implements slog.Handler
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make(map[string]string, len(h.attrs)+record.NumAttrs())
+	for _, attr := range h.attrs {
+		attrs[attr.Key] = attr.Value.String()
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs[attr.Key] = attr.Value.String()
+		return true
+	})
+	h.recorder.observe(record.Time, attrs)
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{
+		Handler:  h.Handler.WithAttrs(attrs),
+		recorder: h.recorder,
+		attrs:    append(slices.Clip(h.attrs), attrs...),
+	}
+}
+
+// WithGroup implements slog.Handler. Grouped attributes are not the
+// loggers' connection fields, so the group's records are not recorded.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return h.Handler.WithGroup(name)
+}
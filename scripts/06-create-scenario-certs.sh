@@ -0,0 +1,163 @@
+#!/bin/bash
+
+set -e
+
+# 06-create-scenario-certs.sh
+# Creates the certificates for the negative-path demo scenarios
+# (/api/demo?scenario=) and stores them as secrets in the demo namespace:
+#
+#   frontend-scenario-certs
+#     impostor-identity     valid SVID for spiffe://example.org/ns/demo/sa/impostor
+#     expired-cert          SVID for the frontend's own SPIFFE ID, already expired
+#     wrong-trust-domain    SVID from a CA outside the example.org trust domain
+#     bundle.pem            SPIRE trust bundle, to verify the backend
+#   backend-scenario-certs
+#     unauthorized-db-client  valid SVID whose CN has no PostgreSQL user mapping
+#
+# Run after 05-register-entries.sh. The SVIDs are minted directly by the
+# SPIRE server; no registration entries are created for them. They last
+# SCENARIO_SVID_TTL (default 24h, the server's ca_ttl, which caps it); once
+# one expires its scenario reports scenario-unavailable until this script
+# is rerun.
+
+# Colors for output
+GREEN='\033[0;32m'
+YELLOW='\033[1;33m'
+RED='\033[0;31m'
+NC='\033[0m' # No Color
+
+echo "=========================================="
+echo "Negative-Path Scenario Certificates"
+echo "=========================================="
+echo ""
+
+for tool in kubectl openssl; do
+  if ! command -v "$tool" &> /dev/null; then
+    echo -e "${RED}✗ $tool not found${NC}"
+    exit 1
+  fi
+done
+
+SCENARIO_SVID_TTL=${SCENARIO_SVID_TTL:-24h}
+
+WORK_DIR=$(mktemp -d)
+trap 'rm -rf "$WORK_DIR"' EXIT
+
+# mint_svid writes <name>.pem and <name>-key.pem for a SPIFFE ID minted by
+# the SPIRE server. The first DNS name becomes the certificate's CN.
+mint_svid() {
+  local name=$1
+  local spiffe_id=$2
+  local dns_name=$3
+  local ttl=$4
+
+  echo "Minting $name"
+  echo "  SPIFFE ID: $spiffe_id"
+  echo "  TTL: $ttl"
+
+  # The server image has no shell, so split the text output locally
+  if ! kubectl exec -n spire-system spire-server-0 -- \
+    /opt/spire/bin/spire-server x509 mint \
+    -spiffeID "$spiffe_id" \
+    -dns "$dns_name" \
+    -ttl "$ttl" > "$WORK_DIR/$name.out"; then
+    echo -e "${RED}  ✗ Failed to mint SVID${NC}"
+    return 1
+  fi
+
+  awk -v cert="$WORK_DIR/$name.pem" -v key="$WORK_DIR/$name-key.pem" '
+    /^X509-SVID:/   { out = cert; next }
+    /^Private key:/ { out = key; next }
+    /^Root CAs:/    { out = ""; next }
+    /^-----BEGIN/   { in_block = 1 }
+    in_block && out != "" { print > out }
+    /^-----END/     { in_block = 0 }
+  ' "$WORK_DIR/$name.out"
+
+  if [ ! -s "$WORK_DIR/$name.pem" ] || [ ! -s "$WORK_DIR/$name-key.pem" ]; then
+    echo -e "${RED}  ✗ Could not parse the minted SVID${NC}"
+    return 1
+  fi
+  echo -e "${GREEN}  ✓ Minted${NC}"
+}
+
+echo "Step 1: Fetching the SPIRE trust bundle..."
+kubectl exec -n spire-system spire-server-0 -- \
+  /opt/spire/bin/spire-server bundle show > "$WORK_DIR/bundle.pem"
+echo -e "${GREEN}✓ Trust bundle fetched${NC}"
+echo ""
+
+echo "Step 2: Minting SVIDs from the SPIRE server..."
+# Envoy RBAC on the backend only allows the frontend: expect 403
+mint_svid impostor-identity \
+  "spiffe://example.org/ns/demo/sa/impostor" \
+  "impostor.demo.svc.cluster.local" \
+  "$SCENARIO_SVID_TTL"
+
+# The frontend's own identity, but expired by the time it is used
+mint_svid expired-cert \
+  "spiffe://example.org/ns/demo/sa/frontend" \
+  "frontend.demo.svc.cluster.local" \
+  "1s"
+
+# PostgreSQL maps only the backend's CN to a database user
+mint_svid unauthorized-db-client \
+  "spiffe://example.org/ns/demo/sa/impostor" \
+  "impostor.demo.svc.cluster.local" \
+  "$SCENARIO_SVID_TTL"
+echo ""
+
+echo "Step 3: Creating an SVID from another trust domain..."
+openssl req -x509 -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes \
+  -keyout "$WORK_DIR/other-ca-key.pem" -out "$WORK_DIR/other-ca.pem" \
+  -days 30 -subj "/O=Other Trust Domain" \
+  -addext "basicConstraints=critical,CA:TRUE" \
+  -addext "keyUsage=critical,keyCertSign,cRLSign" \
+  -addext "subjectAltName=URI:spiffe://other.example" 2>/dev/null
+
+openssl req -newkey ec -pkeyopt ec_paramgen_curve:P-256 -nodes \
+  -keyout "$WORK_DIR/wrong-trust-domain-key.pem" -out "$WORK_DIR/wrong-trust-domain.csr" \
+  -subj "/O=Other Trust Domain" 2>/dev/null
+
+cat > "$WORK_DIR/wrong-trust-domain.ext" <<EOF
+basicConstraints=critical,CA:FALSE
+keyUsage=critical,digitalSignature,keyEncipherment,keyAgreement
+extendedKeyUsage=serverAuth,clientAuth
+subjectAltName=URI:spiffe://other.example/ns/demo/sa/frontend
+EOF
+
+openssl x509 -req -in "$WORK_DIR/wrong-trust-domain.csr" \
+  -CA "$WORK_DIR/other-ca.pem" -CAkey "$WORK_DIR/other-ca-key.pem" -CAcreateserial \
+  -days 30 -extfile "$WORK_DIR/wrong-trust-domain.ext" \
+  -out "$WORK_DIR/wrong-trust-domain.pem" 2>/dev/null
+echo -e "${GREEN}✓ Created spiffe://other.example/ns/demo/sa/frontend${NC}"
+echo ""
+
+# Let the expired SVID's 1s TTL pass before anyone can use it
+sleep 2
+
+echo "Step 4: Storing the certificates as secrets..."
+kubectl create secret generic frontend-scenario-certs -n demo \
+  --from-file=impostor-identity.pem="$WORK_DIR/impostor-identity.pem" \
+  --from-file=impostor-identity-key.pem="$WORK_DIR/impostor-identity-key.pem" \
+  --from-file=expired-cert.pem="$WORK_DIR/expired-cert.pem" \
+  --from-file=expired-cert-key.pem="$WORK_DIR/expired-cert-key.pem" \
+  --from-file=wrong-trust-domain.pem="$WORK_DIR/wrong-trust-domain.pem" \
+  --from-file=wrong-trust-domain-key.pem="$WORK_DIR/wrong-trust-domain-key.pem" \
+  --from-file=bundle.pem="$WORK_DIR/bundle.pem" \
+  --dry-run=client -o yaml | kubectl apply -f -
+
+kubectl create secret generic backend-scenario-certs -n demo \
+  --from-file=unauthorized-db-client.pem="$WORK_DIR/unauthorized-db-client.pem" \
+  --from-file=unauthorized-db-client-key.pem="$WORK_DIR/unauthorized-db-client-key.pem" \
+  --dry-run=client -o yaml | kubectl apply -f -
+echo -e "${GREEN}✓ Secrets created${NC}"
+echo ""
+
+echo -e "${YELLOW}Secret volumes refresh within about a minute; the services read the"
+echo -e "certificates on every scenario run, so no restart is needed.${NC}"
+echo ""
+echo "Try the scenarios against the frontend NodePort:"
+for scenario in impostor-identity expired-cert wrong-trust-domain unauthorized-db-client; do
+  echo "  curl -s 'http://localhost:8080/api/demo?scenario=$scenario' | jq .scenario"
+done